@@ -1,7 +1,22 @@
 module github.com/darmiel/go-cf-client
 
-go 1.22.1
+go 1.23.0
 
-require github.com/go-resty/resty/v2 v2.12.0
+require (
+	github.com/go-resty/resty/v2 v2.12.0
+	go.opentelemetry.io/otel v1.33.0
+	go.opentelemetry.io/otel/metric v1.33.0
+	go.opentelemetry.io/otel/sdk v1.33.0
+	go.opentelemetry.io/otel/sdk/metric v1.33.0
+	go.opentelemetry.io/otel/trace v1.33.0
+	golang.org/x/oauth2 v0.24.0
+)
 
-require golang.org/x/net v0.22.0 // indirect
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
@@ -0,0 +1,108 @@
+// Package redact removes secrets from requests and responses before they are logged or recorded
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	// sensitiveHeaders are the headers which carry credentials
+	sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+	// sensitiveFields are query parameters, form fields and JSON fields which carry credentials,
+	// e.g. the credentials of token requests and the tokens of their responses
+	sensitiveFields = []string{
+		"password", "passcode", "assertion", "client_secret",
+		"access_token", "refresh_token", "id_token",
+	}
+)
+
+// IsSensitive returns true if the value of the query parameter, form field or JSON field with the given name
+// must be redacted
+func IsSensitive(name string) bool {
+	return slices.Contains(sensitiveFields, strings.ToLower(name))
+}
+
+// IsSensitiveHeader returns true if the value of the header with the given name must be redacted
+func IsSensitiveHeader(name string) bool {
+	return slices.Contains(sensitiveHeaders, http.CanonicalHeaderKey(name))
+}
+
+// Header returns a copy of the header with the values of all sensitive headers replaced by replacement
+func Header(header http.Header, replacement string) http.Header {
+	result := header.Clone()
+	for key, values := range result {
+		if IsSensitiveHeader(key) {
+			for i := range values {
+				values[i] = replacement
+			}
+		}
+	}
+	return result
+}
+
+// Values replaces the values of all sensitive parameters of the query or form in place
+func Values(values url.Values, replacement string) {
+	for key, items := range values {
+		if IsSensitive(key) {
+			for i := range items {
+				items[i] = replacement
+			}
+		}
+	}
+}
+
+// URL returns the URL with the values of all sensitive query parameters replaced by replacement.
+// URLs which cannot be parsed are returned as they are
+func URL(rawURL, replacement string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for key := range query {
+		if IsSensitive(key) {
+			Values(query, replacement)
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return rawURL
+}
+
+// JSON returns the JSON body with the values of all sensitive fields replaced by replacement.
+// The second return value is false if the body is not JSON, in which case it is returned as it is
+func JSON(body []byte, replacement string) ([]byte, bool) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body, false
+	}
+	data, err := json.Marshal(Value(value, replacement))
+	if err != nil {
+		return body, false
+	}
+	return data, true
+}
+
+// Value replaces all sensitive fields of the decoded JSON value in place and returns it
+func Value(value any, replacement string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if IsSensitive(key) {
+				v[key] = replacement
+			} else {
+				v[key] = Value(field, replacement)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = Value(item, replacement)
+		}
+	}
+	return value
+}
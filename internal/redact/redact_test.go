@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://uaa/oauth/token?grant_type=password&Password=secret", "https://uaa/oauth/token?Password=x&grant_type=password"},
+		{"https://api/v3/spaces?names=a&page=2", "https://api/v3/spaces?names=a&page=2"},
+		{"://invalid?password=secret", "://invalid?password=secret"},
+	}
+	for _, tt := range tests {
+		if got := URL(tt.url, "x"); got != tt.want {
+			t.Errorf("URL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header := http.Header{"Authorization": {"bearer token"}, "Set-Cookie": {"a", "b"}, "Accept": {"*/*"}}
+	got := Header(header, "x")
+	if got.Get("Authorization") != "x" || len(got.Values("Set-Cookie")) != 2 || got.Values("Set-Cookie")[1] != "x" ||
+		got.Get("Accept") != "*/*" {
+		t.Errorf("Header() = %v, want the credentials redacted", got)
+	}
+	if header.Get("Authorization") != "bearer token" {
+		t.Errorf("Header() modified the original header: %v", header)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body := `{"access_token":"a","nested":[{"client_secret":"b","name":"c"}]}`
+	got, ok := JSON([]byte(body), "x")
+	if want := `{"access_token":"x","nested":[{"client_secret":"x","name":"c"}]}`; !ok || string(got) != want {
+		t.Errorf("JSON() = %s, %v, want %s", got, ok, want)
+	}
+	if got, ok := JSON([]byte("password=a"), "x"); ok || string(got) != "password=a" {
+		t.Errorf("JSON() = %s, %v, want the body unchanged", got, ok)
+	}
+}
@@ -0,0 +1,160 @@
+package cf_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// concurrentRequests is the number of requests which are sent at the same time
+const concurrentRequests = 20
+
+// listConcurrently lists the spaces using concurrentRequests concurrent requests and fails on any error
+func listConcurrently(t *testing.T, client *cf.CloudFoundryClient) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentRequests)
+	start := make(chan struct{})
+	for range concurrentRequests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("request failed: %v", err)
+	}
+}
+
+// assertGrants fails if the UAA did not issue exactly the given grants
+func assertGrants(t *testing.T, server *cftest.Server, want ...string) {
+	t.Helper()
+	if got := server.TokenGrants(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("grants = %v, want %v", got, want)
+	}
+}
+
+func TestConcurrentRequestsShareTokenRefresh(t *testing.T) {
+	// the token expires TokenExpirySafetyMargin + 1s after it has been issued
+	server := newServer(t, 1, cftest.WithTokenExpiry(cf.TokenExpirySafetyMargin+time.Second))
+	// slow token requests keep the refresh in flight while the other requests need a token
+	server.InjectFault(cftest.Fault{Path: "/oauth/token", Delay: 50 * time.Millisecond})
+	client := newClient(t, server)
+
+	listConcurrently(t, client)
+	assertGrants(t, server, "password")
+
+	time.Sleep(time.Second + 100*time.Millisecond)
+	if !client.TokenIsExpired() {
+		t.Fatal("token is not expired")
+	}
+	listConcurrently(t, client)
+	assertGrants(t, server, "password", "refresh_token")
+}
+
+func TestConcurrentRequestsShareRefreshOfRejectedToken(t *testing.T) {
+	server := newServer(t, 1)
+	server.InjectFault(cftest.Fault{Path: "/oauth/token", Delay: 50 * time.Millisecond})
+	client := newClient(t, server)
+	listConcurrently(t, client)
+
+	// the server rejects the token although it is still valid according to the client
+	server.ExpireTokens()
+	listConcurrently(t, client)
+	assertGrants(t, server, "password", "refresh_token")
+
+	server.ExpireTokens()
+	listConcurrently(t, client)
+	assertGrants(t, server, "password", "refresh_token", "refresh_token")
+}
+
+func TestRevokedRefreshTokenFallsBackToGrant(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server)
+
+	// the rejected refresh token is not issued, so only the second password grant is recorded
+	server.RevokeTokens()
+	listConcurrently(t, client)
+	assertGrants(t, server, "password", "password")
+}
+
+// countRequests returns a transport wrapper which counts the requests sent to the given path
+func countRequests(path string, count *atomic.Int32) func(next http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == path {
+				count.Add(1)
+			}
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+func TestRejectedTokenIsReplayedOnce(t *testing.T) {
+	var requests atomic.Int32
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithTransportWrapper(countRequests("/v3/spaces", &requests)))
+
+	// the rejected request is sent again once with the refreshed token
+	server.ExpireTokens()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("%d requests were sent, want the rejected request and one replay", got)
+	}
+	assertGrants(t, server, "password", "refresh_token")
+
+	// if the refreshed token is rejected as well, the request is not replayed again
+	requests.Store(0)
+	server.InjectFault(cftest.Fault{
+		Path:   "/v3/spaces",
+		Status: http.StatusUnauthorized,
+		Errors: []cf.CloudFoundryError{{Code: cf.InvalidAuthTokenErrorCode, Title: "CF-InvalidAuthToken"}},
+	})
+	_, err := client.ListSpaces(cf.ListSpacesOptions{})
+	if !errors.Is(err, cf.UnauthorizedErr) {
+		t.Errorf("err = %v, want UnauthorizedErr", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("%d requests were sent, want the rejected request and one replay", got)
+	}
+	assertGrants(t, server, "password", "refresh_token", "refresh_token")
+}
+
+func TestRevokedRefreshTokenRequiresReauthenticationWithoutReusableGrant(t *testing.T) {
+	server := newServer(t, 1, cftest.WithPasscode("one-time-passcode"))
+	config := server.Config()
+	config.GrantType = cf.PasscodeGrant
+	config.Passcode = "one-time-passcode"
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the passcode has been used up, so the client can't request a new token without the refresh token
+	server.RevokeTokens()
+	for range 2 {
+		if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.Is(err, cf.ReauthenticationRequiredErr) {
+			t.Errorf("err = %v, want ReauthenticationRequiredErr", err)
+		}
+	}
+	assertGrants(t, server, "password")
+}
@@ -0,0 +1,114 @@
+package cf
+
+import (
+	"context"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+)
+
+// Requester sends requests to the Cloud Controller. It is implemented by CloudFoundryClient
+// and accepted by the generic helpers like GetPaginated and GetResult
+type Requester interface {
+	SendRequest(method string, path any, modifiers ...RequestModifier) (*resty.Response, error)
+	SendRequestWithContext(ctx context.Context, method string, path any, modifiers ...RequestModifier) (*resty.Response, error)
+}
+
+// InfoAPI contains the requests for the information about the foundation
+type InfoAPI interface {
+	GetRoot() (*models.Root, error)
+	GetRootWithContext(ctx context.Context) (*models.Root, error)
+	GetInfo() (*models.Info, error)
+	GetInfoWithContext(ctx context.Context) (*models.Info, error)
+}
+
+// JobsAPI contains the requests for asynchronous jobs
+type JobsAPI interface {
+	GetJob(jobGUID string) (*models.Job, error)
+	GetJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error)
+	WaitForJob(jobGUID string) (*models.Job, error)
+	WaitForJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error)
+}
+
+// OrganizationsAPI contains the requests for organizations
+type OrganizationsAPI interface {
+	ListOrganizations(options ListOrganizationsOptions) ([]models.Organization, error)
+	ListOrganizationsWithContext(ctx context.Context, options ListOrganizationsOptions) ([]models.Organization, error)
+	ListOrganizationsIterator(options ListOrganizationsOptions) *PageIterator[models.Organization]
+}
+
+// SpacesAPI contains the requests for spaces
+type SpacesAPI interface {
+	ListSpaces(options ListSpacesOptions) ([]models.Space, error)
+	ListSpacesWithContext(ctx context.Context, options ListSpacesOptions) ([]models.Space, error)
+	ListSpacesIterator(options ListSpacesOptions) *PageIterator[models.Space]
+	ListSpacesEnriched(options ListSpacesOptions) ([]models.EnrichedSpace, error)
+	ListSpacesEnrichedWithContext(ctx context.Context, options ListSpacesOptions) ([]models.EnrichedSpace, error)
+	GetSpace(guid string) (*models.Space, error)
+	GetSpaceWithContext(ctx context.Context, guid string) (*models.Space, error)
+	GetSpaceEnriched(guid string) (*models.EnrichedSpace, error)
+	GetSpaceEnrichedWithContext(ctx context.Context, guid string) (*models.EnrichedSpace, error)
+	CreateSpace(name, orgGUID string, options CreateSpaceOptions) (*models.Space, error)
+	CreateSpaceWithContext(ctx context.Context, name, orgGUID string, options CreateSpaceOptions) (*models.Space, error)
+	UpdateSpace(guid string, options UpdateSpaceOptions) (*models.Space, error)
+	UpdateSpaceWithContext(ctx context.Context, guid string, options UpdateSpaceOptions) (*models.Space, error)
+	ListUsersForSpace(spaceGUID string, options ListUsersForSpaceOptions) ([]models.User, error)
+	ListUsersForSpaceWithContext(
+		ctx context.Context,
+		spaceGUID string,
+		options ListUsersForSpaceOptions,
+	) ([]models.User, error)
+	ListUsersForSpaceIterator(spaceGUID string, options ListUsersForSpaceOptions) *PageIterator[models.User]
+}
+
+// UsersAPI contains the requests for users
+type UsersAPI interface {
+	ListUsers(options ListUsersOptions) ([]models.User, error)
+	ListUsersWithContext(ctx context.Context, options ListUsersOptions) ([]models.User, error)
+	ListUsersIterator(options ListUsersOptions) *PageIterator[models.User]
+	GetUser(userGUID string) (*models.User, error)
+	GetUserWithContext(ctx context.Context, userGUID string) (*models.User, error)
+	CreateUser(guid string, options CreateUserOptions) (*models.User, error)
+	CreateUserWithContext(ctx context.Context, guid string, options CreateUserOptions) (*models.User, error)
+	UpdateUser(guid string, metadata *models.MetadataPatch) (*models.User, error)
+	UpdateUserWithContext(ctx context.Context, guid string, metadata *models.MetadataPatch) (*models.User, error)
+	DeleteUser(userGUID string) (*JobHandle, error)
+	DeleteUserWithContext(ctx context.Context, userGUID string) (*JobHandle, error)
+}
+
+// RolesAPI contains the requests for roles
+type RolesAPI interface {
+	ListRole(options ListRoleOptions) ([]models.Role, error)
+	ListRoleWithContext(ctx context.Context, options ListRoleOptions) ([]models.Role, error)
+	ListRoleIterator(options ListRoleOptions) *PageIterator[models.Role]
+	ListRoleEnriched(options ListRoleOptions) ([]models.EnrichedRole, error)
+	ListRoleEnrichedWithContext(ctx context.Context, options ListRoleOptions) ([]models.EnrichedRole, error)
+	GetRole(roleGUID string) (*models.Role, error)
+	GetRoleWithContext(ctx context.Context, roleGUID string) (*models.Role, error)
+	GetRoleEnriched(roleGUID string, include ...Include) (*models.EnrichedRole, error)
+	GetRoleEnrichedWithContext(ctx context.Context, roleGUID string, include ...Include) (*models.EnrichedRole, error)
+	CreateRole(role Role, spaceOrOrganizationGUID string, options CreateRoleOptions) (*models.Role, error)
+	CreateRoleWithContext(
+		ctx context.Context,
+		role Role,
+		spaceOrOrganizationGUID string,
+		options CreateRoleOptions,
+	) (*models.Role, error)
+	DeleteRole(roleGUID string) (*JobHandle, error)
+	DeleteRoleWithContext(ctx context.Context, roleGUID string) (*JobHandle, error)
+}
+
+// CloudFoundryAPI contains all requests of the client grouped by resource.
+// Depend on this interface (or one of the resource interfaces) instead of *CloudFoundryClient
+// to replace the client in tests, e.g. with cftest.Mock
+type CloudFoundryAPI interface {
+	Requester
+	InfoAPI
+	JobsAPI
+	OrganizationsAPI
+	SpacesAPI
+	UsersAPI
+	RolesAPI
+}
+
+// make sure the client implements all interfaces
+var _ CloudFoundryAPI = (*CloudFoundryClient)(nil)
@@ -0,0 +1,289 @@
+package cf
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"golang.org/x/oauth2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	CLINotLoggedInErr = errors.New("the CF CLI is not logged in, run `cf login` first")
+)
+
+// CLIOrganizationFields is the targeted organization in the CF CLI config
+type CLIOrganizationFields struct {
+	GUID string `json:"GUID"`
+	Name string `json:"Name"`
+}
+
+// CLISpaceFields is the targeted space in the CF CLI config
+type CLISpaceFields struct {
+	GUID     string `json:"GUID"`
+	Name     string `json:"Name"`
+	AllowSSH bool   `json:"AllowSSH"`
+}
+
+// CLIConfig is the config written by the official CF CLI (`cf login`) to ~/.cf/config.json
+// Only the fields relevant for this client are parsed, all other fields are kept as they are when saving.
+type CLIConfig struct {
+	Target                string                `json:"Target"`
+	AuthorizationEndpoint string                `json:"AuthorizationEndpoint"`
+	UaaEndpoint           string                `json:"UaaEndpoint"`
+	AccessToken           string                `json:"AccessToken"`
+	RefreshToken          string                `json:"RefreshToken"`
+	UAAOAuthClient        string                `json:"UAAOAuthClient"`
+	UAAOAuthClientSecret  string                `json:"UAAOAuthClientSecret"`
+	UAAGrantType          string                `json:"UAAGrantType"`
+	SSLDisabled           bool                  `json:"SSLDisabled"`
+	OrganizationFields    CLIOrganizationFields `json:"OrganizationFields"`
+	SpaceFields           CLISpaceFields        `json:"SpaceFields"`
+
+	// PersistTokens writes every token requested by a client created from this config back to the config file,
+	// so the CLI and other tools can use the refreshed token
+	PersistTokens bool `json:"-"`
+
+	path string
+	// mu guards writing the config file
+	mu sync.Mutex
+}
+
+// CLIConfigPath returns the path of the CF CLI config, which is $CF_HOME/.cf/config.json or ~/.cf/config.json
+func CLIConfigPath() (string, error) {
+	home := os.Getenv("CF_HOME")
+	if home == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(home, ".cf", "config.json"), nil
+}
+
+// LoadCLIConfig loads the CF CLI config from the given path.
+// If path is empty, the default location of the config is used (see CLIConfigPath)
+func LoadCLIConfig(path string) (*CLIConfig, error) {
+	if path == "" {
+		var err error
+		if path, err = CLIConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res := &CLIConfig{path: path}
+	if err = json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CloudFoundryConfig returns a CloudFoundryConfig for the target of the CLI config
+func (c *CLIConfig) CloudFoundryConfig() *CloudFoundryConfig {
+	cfg := &CloudFoundryConfig{
+		Organization:      c.OrganizationFields.Name,
+		APIEndpoint:       strings.TrimSuffix(c.Target, "/"),
+		AuthEndpoint:      strings.TrimSuffix(c.AuthorizationEndpoint, "/"),
+		UAAEndpoint:       strings.TrimSuffix(c.UaaEndpoint, "/"),
+		OAuthClientID:     c.UAAOAuthClient,
+		OAuthClientSecret: c.UAAOAuthClientSecret,
+		SkipSSLValidation: c.SSLDisabled,
+	}
+	if c.UAAGrantType == string(ClientCredentialsGrant) {
+		cfg.GrantType = ClientCredentialsGrant
+	}
+	return cfg
+}
+
+// Token returns the token stored in the CLI config.
+// The expiry is read from the access token, which is a JWT
+func (c *CLIConfig) Token() (*oauth2.Token, error) {
+	if c.AccessToken == "" && c.RefreshToken == "" {
+		return nil, CLINotLoggedInErr
+	}
+	token := &oauth2.Token{
+		TokenType:    "bearer",
+		AccessToken:  c.AccessToken,
+		RefreshToken: c.RefreshToken,
+	}
+	// the CLI stores the access token including its type, e.g. "bearer eyJ..."
+	if tokenType, accessToken, ok := strings.Cut(c.AccessToken, " "); ok {
+		token.TokenType = tokenType
+		token.AccessToken = accessToken
+	}
+	token.Expiry = jwtExpiry(token.AccessToken)
+	return token, nil
+}
+
+// TokenSource returns a TokenSource which refreshes the token stored in the CLI config.
+// If the CLI was logged in using client credentials, these are used if the refresh token is rejected.
+// If PersistTokens is set, every new token is written back to the config file
+func (c *CLIConfig) TokenSource() (TokenSource, error) {
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	cfg := c.CloudFoundryConfig()
+	source := &uaaTokenSource{
+		config: cfg,
+		token:  token,
+	}
+	if cfg.GrantType == ClientCredentialsGrant {
+		source.grant = map[string]string{
+			"grant_type": string(ClientCredentialsGrant),
+		}
+		source.reusableGrant = true
+	}
+	if !c.PersistTokens {
+		return source, nil
+	}
+	return &cliPersistingTokenSource{source: source, config: c}, nil
+}
+
+// NewClient returns a new client for the target of the CLI config which reuses the stored token
+func (c *CLIConfig) NewClient(options ...ClientOption) (*CloudFoundryClient, error) {
+	return c.NewClientWithContext(context.Background(), options...)
+}
+
+// NewClientWithContext is like NewClient but uses the given context if the stored token needs to be refreshed
+func (c *CLIConfig) NewClientWithContext(ctx context.Context, options ...ClientOption) (*CloudFoundryClient, error) {
+	source, err := c.TokenSource()
+	if err != nil {
+		return nil, err
+	}
+	initialOptions := []ClientOption{WithTokenSource(source)}
+	// reuse the stored access token if we know when it expires, otherwise request a new one right away
+	if token, _ := c.Token(); token.AccessToken != "" && !token.Expiry.IsZero() {
+		initialOptions = append(initialOptions, withToken(token))
+	}
+	return c.CloudFoundryConfig().NewClientWithContext(ctx, append(initialOptions, options...)...)
+}
+
+// SetToken updates the tokens of the CLI config
+func (c *CLIConfig) SetToken(token *oauth2.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.AccessToken = strings.ToLower(token.Type()) + " " + token.AccessToken
+	c.RefreshToken = token.RefreshToken
+}
+
+// Save writes the tokens of the CLI config back to the file it was loaded from.
+// All other fields of the file and its mode are kept as they are
+func (c *CLIConfig) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the file is replaced, so the mode of the existing file has to be kept explicitly
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range map[string]string{
+		"AccessToken":  c.AccessToken,
+		"RefreshToken": c.RefreshToken,
+	} {
+		if raw[key], err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+	if data, err = json.MarshalIndent(raw, "", "  "); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data, info.Mode().Perm())
+}
+
+// writeFileAtomic writes the data to a temporary file in the directory of path and renames it to path,
+// so readers (e.g. the CF CLI) never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	// the temporary file is removed if anything fails before it has been renamed
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if err = file.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// cliPersistingTokenSource is a TokenSource which writes every new token back to the CLI config
+type cliPersistingTokenSource struct {
+	source TokenSource
+	config *CLIConfig
+}
+
+// Token requests a new token and writes it to the CLI config
+func (s *cliPersistingTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext is like Token but uses the given context for the token request
+func (s *cliPersistingTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	token, err := tokenFromSource(ctx, s.source)
+	if err != nil {
+		return nil, err
+	}
+	s.config.SetToken(token)
+	if err = s.config.Save(); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// withToken is a client option that sets the initial token of the client
+func withToken(token *oauth2.Token) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.authToken = token
+	}
+}
+
+// jwtExpiry returns the expiry of the given JWT without verifying it, or the zero time if it can't be determined
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,247 @@
+package cf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Error codes of the Cloud Controller which are commonly checked for
+// see https://github.com/cloudfoundry/cloud_controller_ng/blob/main/errors/v2.yml
+//
+//goland:noinspection GoUnusedConst
+const (
+	InvalidAuthTokenErrorCode = 1000
+	NotAuthenticatedErrorCode = 10002
+	NotAuthorizedErrorCode    = 10003
+	ResourceNotFoundErrorCode = 10010
+	UniquenessErrorCode       = 10016
+)
+
+// Sentinel errors which can be used with errors.Is to check an error returned by the client
+var (
+	NotFoundErr     = errors.New("resource not found")
+	UniquenessErr   = errors.New("resource is not unique")
+	ForbiddenErr    = errors.New("forbidden")
+	UnauthorizedErr = errors.New("unauthorized")
+
+	// ReauthenticationRequiredErr is returned if the token can neither be refreshed
+	// nor be requested again because the TokenSource has no (reusable) credentials left
+	ReauthenticationRequiredErr = errors.New("re-authentication required")
+)
+
+// CloudFoundryError is a struct that represents an error response from the server
+type CloudFoundryError struct {
+	Detail string `json:"detail"`
+	Title  string `json:"title"`
+	Code   int    `json:"code"`
+}
+
+// Error returns a string representation of the error
+func (c CloudFoundryError) Error() string {
+	return fmt.Sprintf("CF-Error[%d] %s: %s", c.Code, c.Title, c.Detail)
+}
+
+// Is reports whether the error matches one of the sentinel errors based on its code
+func (c CloudFoundryError) Is(target error) bool {
+	switch target {
+	case NotFoundErr:
+		return c.Code == ResourceNotFoundErrorCode
+	case UniquenessErr:
+		return c.Code == UniquenessErrorCode
+	case ForbiddenErr:
+		return c.Code == NotAuthorizedErrorCode
+	case UnauthorizedErr:
+		return c.Code == NotAuthenticatedErrorCode || c.Code == InvalidAuthTokenErrorCode
+	}
+	return false
+}
+
+// APIError is returned for every response with a status code >= 400.
+// It contains all errors returned by the server as well as information about the failed request.
+// Use errors.As to extract a single CloudFoundryError or errors.Is to check for a sentinel error.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Method is the HTTP method of the request
+	Method string
+
+	// URL is the URL of the request
+	URL string
+
+	// RequestID is the value of the X-Vcap-Request-Id header of the response
+	RequestID string
+
+	// Errors contains all errors returned by the server
+	Errors []CloudFoundryError
+
+	// Body is the raw body of the response if it could not be parsed into errors
+	Body []byte
+}
+
+// Error returns a string representation of the error
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("api error (%d %s %s", e.StatusCode, e.Method, e.URL))
+	if e.RequestID != "" {
+		sb.WriteString(", request id " + e.RequestID)
+	}
+	sb.WriteString(")")
+	if len(e.Errors) == 0 {
+		sb.WriteString(fmt.Sprintf(". body: %s", e.Body))
+		return sb.String()
+	}
+	var messages []string
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	sb.WriteString(". errors: " + strings.Join(messages, ", "))
+	return sb.String()
+}
+
+// Unwrap returns all errors returned by the server
+func (e *APIError) Unwrap() []error {
+	result := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		result[i] = err
+	}
+	return result
+}
+
+// Is reports whether the error matches one of the sentinel errors based on its status code.
+// The codes of the contained errors are checked by errors.Is through Unwrap
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case NotFoundErr:
+		return e.StatusCode == http.StatusNotFound
+	case ForbiddenErr:
+		return e.StatusCode == http.StatusForbidden
+	case UnauthorizedErr:
+		return e.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// HasCode returns true if any of the errors returned by the server has the given code
+func (e *APIError) HasCode(code int) bool {
+	return slices.ContainsFunc(e.Errors, func(err CloudFoundryError) bool {
+		return err.Code == code
+	})
+}
+
+// IsNotFound returns true if the error indicates that a resource was not found (CF-ResourceNotFound, 404)
+func IsNotFound(err error) bool {
+	return errors.Is(err, NotFoundErr)
+}
+
+// IsUniqueness returns true if the error indicates that a resource already exists (CF-UniquenessError)
+func IsUniqueness(err error) bool {
+	return errors.Is(err, UniquenessErr)
+}
+
+// IsForbidden returns true if the error indicates that the user is not allowed to perform the action (CF-NotAuthorized, 403)
+func IsForbidden(err error) bool {
+	return errors.Is(err, ForbiddenErr)
+}
+
+// IsUnauthorized returns true if the error indicates that the request was not authenticated (CF-NotAuthenticated, 401)
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, UnauthorizedErr)
+}
+
+// TokenError is returned if the UAA rejects a token request
+type TokenError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// ErrorCode is the OAuth error code, e.g. "invalid_token" or "invalid_grant"
+	ErrorCode string `json:"error"`
+
+	// Description is the human-readable description of the error
+	Description string `json:"error_description"`
+}
+
+// Error returns a string representation of the error
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("token error (%d) %s: %s", e.StatusCode, e.ErrorCode, e.Description)
+}
+
+// Is reports whether the error matches UnauthorizedErr
+func (e *TokenError) Is(target error) bool {
+	return target == UnauthorizedErr && e.StatusCode == http.StatusUnauthorized
+}
+
+// isRejectedGrant returns true if the UAA rejected the grant itself (e.g. an expired or revoked refresh token)
+// in contrast to transport or server errors
+func (e *TokenError) isRejectedGrant() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnauthorized
+}
+
+// JobFailedError is returned if an asynchronous job failed
+type JobFailedError struct {
+	// Job is the failed job including its errors and warnings
+	Job *models.Job
+}
+
+// Error returns a string representation of the error
+func (e *JobFailedError) Error() string {
+	var messages []string
+	for _, err := range e.Unwrap() {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("job %s (%s) failed: %s", e.Job.Guid, e.Job.Operation, strings.Join(messages, ", "))
+}
+
+// Unwrap returns the errors of the job as CloudFoundryError values,
+// so errors.Is and errors.As can be used like for an *APIError
+func (e *JobFailedError) Unwrap() []error {
+	result := make([]error, len(e.Job.Errors))
+	for i, err := range e.Job.Errors {
+		result[i] = CloudFoundryError{Detail: err.Detail, Title: err.Title, Code: err.Code}
+	}
+	return result
+}
+
+// parseTokenErrorResponse returns a *TokenError from the given response of the UAA
+func parseTokenErrorResponse(resp *resty.Response) error {
+	tokenErr := &TokenError{}
+	if err := json.Unmarshal(resp.Body(), tokenErr); err != nil || tokenErr.ErrorCode == "" {
+		tokenErr.ErrorCode = "unknown"
+		tokenErr.Description = string(resp.Body())
+	}
+	tokenErr.StatusCode = resp.StatusCode()
+	return tokenErr
+}
+
+// parseErrorResponse returns an *APIError from the given response
+func parseErrorResponse(resp *resty.Response) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode(),
+		Method:     resp.Request.Method,
+		URL:        resp.Request.URL,
+		RequestID:  resp.Header().Get("X-Vcap-Request-Id"),
+	}
+	body := resp.Body()
+
+	multiError := struct {
+		Errors []CloudFoundryError `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &multiError); err == nil && len(multiError.Errors) > 0 {
+		apiErr.Errors = multiError.Errors
+		return apiErr
+	}
+	var singleError CloudFoundryError
+	if err := json.Unmarshal(body, &singleError); err == nil && singleError != (CloudFoundryError{}) {
+		apiErr.Errors = []CloudFoundryError{singleError}
+		return apiErr
+	}
+	// if we can't parse the error, just keep the raw body
+	apiErr.Body = body
+	return apiErr
+}
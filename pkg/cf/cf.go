@@ -1,18 +1,25 @@
 package cf
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/darmiel/go-cf-client/internal/util"
+	"github.com/darmiel/go-cf-client/pkg/models"
 	"github.com/go-resty/resty/v2"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2"
+	"log/slog"
+	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 )
 
 const (
-	// TokenExpirySafetyMargin is the lastAuthTime to subtract from the authToken expiration lastAuthTime to ensure
+	// TokenExpirySafetyMargin is the time to subtract from the authToken expiration time to ensure
 	// that the authToken is refreshed before it expires
 	TokenExpirySafetyMargin = 5 * time.Second
 
@@ -35,6 +42,30 @@ type (
 	// RequestModifier is a type that represents a request modifier
 	// You can use this type to modify the request before it is executed
 	RequestModifier func(r *resty.Request)
+
+	// ClientOption is a type that represents an option for the client
+	// You can pass client options to NewClient to configure the client before it authenticates
+	ClientOption func(c *CloudFoundryClient)
+
+	// GrantType is a type that represents the OAuth grant used to authenticate against the UAA
+	GrantType string
+)
+
+//goland:noinspection GoUnusedConst
+const (
+	// PasswordGrant authenticates a user using the Username and Password of the config
+	PasswordGrant GrantType = "password"
+
+	// ClientCredentialsGrant authenticates a UAA client using the OAuthClientID and OAuthClientSecret of the config
+	ClientCredentialsGrant GrantType = "client_credentials"
+
+	// PasscodeGrant authenticates a federated user using the one-time Passcode of the config.
+	// The passcode can be obtained from the /passcode page of the login server, see CloudFoundryConfig.PasscodeURL
+	PasscodeGrant GrantType = "passcode"
+
+	// JWTBearerGrant authenticates a federated user by exchanging the JWTAssertion of the config,
+	// which has been issued by a trusted identity provider
+	JWTBearerGrant GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
 )
 
 // CloudFoundryConfig is the configuration for the Cloud Foundry httpClient
@@ -49,7 +80,24 @@ type CloudFoundryConfig struct {
 	OAuthClientID     string `json:"oauth_client_id,omitempty"`
 	OAuthClientSecret string `json:"oauth_client_secret,omitempty"`
 
+	// GrantType is the grant used to authenticate. Defaults to PasswordGrant
+	GrantType GrantType `json:"grant_type,omitempty"`
+
+	// Passcode is the one-time passcode used by the PasscodeGrant
+	Passcode string `json:"passcode,omitempty"`
+
+	// JWTAssertion is the identity provider issued JWT used by the JWTBearerGrant
+	JWTAssertion string `json:"jwt_assertion,omitempty"`
+
+	// Origin is the origin key of the identity provider the user belongs to (e.g. "uaa", "ldap" or "my-saml-idp").
+	// If set, it is sent as login_hint with the password, passcode and JWT bearer grants
+	Origin string `json:"origin,omitempty"`
+
 	UAAEndpoint string `json:"uaa_endpoint,omitempty"`
+
+	// SkipSSLValidation disables the validation of TLS certificates (like the CLI's --skip-ssl-validation).
+	// Only use this for lab environments
+	SkipSSLValidation bool `json:"skip_ssl_validation,omitempty"`
 }
 
 // AuthTokenInfo is the response from the authToken endpoint and will be returned after a successful authentication
@@ -63,28 +111,50 @@ type AuthTokenInfo struct {
 }
 
 // CloudFoundryClient is a struct that manages the authToken and refreshes it if necessary
+// It is safe for concurrent use by multiple goroutines
 type CloudFoundryClient struct {
-	authToken    *AuthTokenInfo
-	lastAuthTime time.Time
-	config       *CloudFoundryConfig
-	httpClient   *resty.Client
-}
+	// tokenMu guards authToken and refreshCall
+	tokenMu     sync.Mutex
+	authToken   *oauth2.Token
+	refreshCall *tokenRefreshCall
+	tokenSource TokenSource
 
-// GetTokenInfo returns a copy of the authToken info
-func (req *CloudFoundryClient) GetTokenInfo() AuthTokenInfo {
-	return *req.authToken
-}
+	config      *CloudFoundryConfig
+	httpClient  *resty.Client
+	retryPolicy RetryPolicy
+
+	// transportOptions are applied to httpClient, which is also used for the token requests
+	transportOptions transportOptions
+
+	jobPollPolicy JobPollPolicy
+
+	logger       *slog.Logger
+	logVerbosity LogVerbosity
 
-// CloudFoundryError is a struct that represents an error response from the server
-type CloudFoundryError struct {
-	Detail string `json:"detail"`
-	Title  string `json:"title"`
-	Code   int    `json:"code"`
+	telemetry telemetry
+
+	// middleware wraps SendRequest, authMiddleware wraps the token and unauthenticated requests
+	middleware     []Middleware
+	authMiddleware []Middleware
+
+	// rateLimiter is nil if rate limiting is disabled
+	rateLimiter *rateLimiter
+
+	// infoMu guards root and info
+	infoMu sync.Mutex
+	root   *models.Root
+	info   *models.Info
 }
 
-// Error returns a string representation of the error
-func (c CloudFoundryError) Error() string {
-	return fmt.Sprintf("CF-Error[%d] %s: %s", c.Code, c.Title, c.Detail)
+// GetTokenInfo returns a copy of the authToken info
+// ExpiresIn is the remaining lifetime of the authToken in seconds
+func (req *CloudFoundryClient) GetTokenInfo() AuthTokenInfo {
+	req.tokenMu.Lock()
+	defer req.tokenMu.Unlock()
+	if req.authToken == nil {
+		return AuthTokenInfo{}
+	}
+	return tokenInfoFromOAuth2Token(req.authToken)
 }
 
 // href is a struct that represents a href in a paginated response
@@ -92,23 +162,29 @@ type href struct {
 	Href string `json:"href"`
 }
 
+// PaginationInfo is a struct that represents the pagination information of a paginated response
+type PaginationInfo struct {
+	TotalResults int   `json:"total_results"`
+	TotalPages   int   `json:"total_pages"`
+	First        *href `json:"first"`
+	Last         *href `json:"last"`
+	Next         *href `json:"next"`
+	Previous     *href `json:"previous"`
+}
+
 // CloudFoundryPaginatedResult is a struct that represents a paginated response from the server
 type CloudFoundryPaginatedResult[T any] struct {
-	Pagination struct {
-		TotalResults int   `json:"total_results"`
-		TotalPages   int   `json:"total_pages"`
-		First        *href `json:"first"`
-		Last         *href `json:"last"`
-		Next         *href `json:"next"`
-		Previous     *href `json:"previous"`
-	}
-	Resources []T
+	Pagination PaginationInfo
+	Resources  []T
 }
 
 // PaginationOptions is a struct that represents the options for the number of items to return per page
 type PaginationOptions struct {
-	// PerPage is the number of organizations to return per page
+	// PerPage is the number of resources to return per page. Defaults to MaxItemsPerPage
 	PerPage int
+
+	// Page is the page to start at. Defaults to the first page
+	Page int
 }
 
 // LoadCloudFoundryConfig loads the config from the `config.json` file
@@ -122,17 +198,18 @@ func LoadCloudFoundryConfig(fileName string) (res *CloudFoundryConfig, err error
 }
 
 // newAuthenticatedRequest is a wrapper around resty's R method which automatically refreshes the authToken if necessary
-func (req *CloudFoundryClient) newAuthenticatedRequest() (*resty.Request, error) {
+// the given context is used for the token refresh and is attached to the returned request
+func (req *CloudFoundryClient) newAuthenticatedRequest(ctx context.Context) (*resty.Request, error) {
 	// refresh the authToken if necessary
-	if req.TokenIsExpired() {
-		if err := req.RefreshToken(); err != nil {
-			return nil, err
-		}
+	token, err := req.validToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 	// fill in authentication headers
 	return req.httpClient.R().
-		SetAuthScheme(req.authToken.TokenType).
-		SetAuthToken(req.authToken.AccessToken), nil
+		SetContext(ctx).
+		SetAuthScheme(token.Type()).
+		SetAuthToken(token.AccessToken), nil
 }
 
 // resolveEndpointURL returns the full URL for the given path
@@ -190,20 +267,127 @@ func (req *CloudFoundryClient) SendRequest(
 	path any,
 	modifiers ...RequestModifier,
 ) (*resty.Response, error) {
-	// newAuthenticatedRequest automatically fills in authentication headers and refreshes the authToken if necessary
-	r, err := req.newAuthenticatedRequest()
-	if err != nil {
-		return nil, err
+	return req.SendRequestWithContext(context.Background(), method, path, modifiers...)
+}
+
+// SendRequestWithContext is like SendRequest but uses the given context for the request and a possible token refresh
+func (req *CloudFoundryClient) SendRequestWithContext(
+	ctx context.Context,
+	method string,
+	path any,
+	modifiers ...RequestModifier,
+) (*resty.Response, error) {
+	r := &MiddlewareRequest{
+		Kind:      APIRequest,
+		Method:    method,
+		URL:       req.config.resolveEndpointURL(path),
+		Modifiers: slices.Clone(modifiers),
 	}
-	applyRequestModifiers(r, modifiers...)
-	resp, err := r.Execute(method, req.config.resolveEndpointURL(path))
-	if err != nil {
-		return nil, err
+	result := handleRequest(ctx, req.middleware, r, req.sendInstrumentedRequest)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.Response, nil
+}
+
+// sendInstrumentedRequest sends the request of the middleware using sendRequest and records it in a span
+// and the request metrics. It is the innermost handler of the middleware of SendRequest
+func (req *CloudFoundryClient) sendInstrumentedRequest(ctx context.Context, r *MiddlewareRequest) MiddlewareResult {
+	attrs := requestAttributes(r.Method, r.URL)
+	ctx, span := req.telemetry.startSpan(ctx, "HTTP "+r.Method, trace.SpanKindClient,
+		append(attrs, semconv.URLFull(redactURL(r.URL)))...)
+	start := time.Now()
+
+	resp, attempts, err := req.sendRequest(ctx, r.Method, r.URL, r.Modifiers...)
+
+	duration := time.Since(start)
+	if resp != nil && resp.RawResponse != nil {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(resp.StatusCode()))
 	}
-	if resp.StatusCode() >= 400 {
-		return nil, parseErrorResponse(resp.Body())
+	attrs = append(attrs, errorTypeAttr(err)...)
+	span.SetAttributes(attrs...)
+	span.SetAttributes(semconv.HTTPRequestResendCount(attempts - 1))
+	req.telemetry.recordRequest(ctx, duration, attempts-1, attrs)
+	endSpan(span, err)
+	return MiddlewareResult{Response: resp, Err: err, Attempts: attempts, Duration: duration}
+}
+
+// sendRequest sends the request to the given URL according to the retry policy
+// and returns the last response (also if it is an error response) as well as the number of attempts
+func (req *CloudFoundryClient) sendRequest(
+	ctx context.Context,
+	method string,
+	url string,
+	modifiers ...RequestModifier,
+) (*resty.Response, int, error) {
+	policy := req.retryPolicy
+	canRetry := policy.allowsRetry(method)
+	reauthenticated := false
+
+	for attempt := 1; ; attempt++ {
+		// newAuthenticatedRequest automatically fills in authentication headers and refreshes the authToken if necessary
+		r, err := req.newAuthenticatedRequest(ctx)
+		if err != nil {
+			return nil, attempt, err
+		}
+		applyRequestModifiers(r, modifiers...)
+		req.telemetry.inject(ctx, r)
+		release, err := req.rateLimiter.acquire(ctx)
+		if err != nil {
+			return nil, attempt, err
+		}
+		start := time.Now()
+		resp, err := r.Execute(method, url)
+		release()
+		req.rateLimiter.observe(resp)
+		req.logRequest(ctx, "cf api request", r, resp, err, time.Since(start))
+
+		// don't retry if the caller is no longer interested in the result
+		if err != nil && ctx.Err() != nil {
+			return nil, attempt, ctx.Err()
+		}
+
+		// the token might have been revoked, so request a new token once and try again.
+		// this doesn't count as an attempt of the retry policy
+		if err == nil && resp.StatusCode() == http.StatusUnauthorized && !reauthenticated {
+			reauthenticated = true
+			req.invalidateToken(r.Token)
+			attempt--
+			continue
+		}
+
+		willRetry := canRetry && attempt < policy.MaxAttempts && policy.shouldRetry(ctx, resp, err)
+		info := RetryAttempt{
+			Attempt:   attempt,
+			Method:    method,
+			URL:       url,
+			Err:       err,
+			WillRetry: willRetry,
+		}
+		if resp != nil && resp.RawResponse != nil {
+			info.StatusCode = resp.StatusCode()
+		}
+		if willRetry {
+			info.Wait = policy.backoff(attempt, resp)
+		}
+		if policy.OnAttempt != nil {
+			policy.OnAttempt(info)
+		}
+
+		if willRetry {
+			if err := sleepContext(ctx, info.Wait); err != nil {
+				return nil, attempt, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, attempt, err
+		}
+		if resp.StatusCode() >= 400 {
+			return resp, attempt, parseErrorResponse(resp)
+		}
+		return resp, attempt, nil
 	}
-	return resp, nil
 }
 
 // SendRequestAndParseResult is a wrapper around SendRequest which automatically sets the result type
@@ -213,12 +397,23 @@ func (req *CloudFoundryClient) SendRequest(
 // :param modifier: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server, parsed as the given type
 func SendRequestAndParseResult[T any](
-	req *CloudFoundryClient,
+	req Requester,
+	method string,
+	path any,
+	modifiers ...RequestModifier,
+) (*T, error) {
+	return SendRequestAndParseResultWithContext[T](context.Background(), req, method, path, modifiers...)
+}
+
+// SendRequestAndParseResultWithContext is like SendRequestAndParseResult but uses the given context
+func SendRequestAndParseResultWithContext[T any](
+	ctx context.Context,
+	req Requester,
 	method string,
 	path any,
 	modifiers ...RequestModifier,
 ) (*T, error) {
-	resp, err := req.SendRequest(method, path, WithResult[T](), WithRequestModifiers(modifiers...))
+	resp, err := req.SendRequestWithContext(ctx, method, path, WithResult[T](), WithRequestModifiers(modifiers...))
 	if err != nil {
 		return nil, err
 	}
@@ -232,31 +427,26 @@ func SendRequestAndParseResult[T any](
 // :param modifier: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The resources from all pages
 func FetchAllPages[T any](
-	req *CloudFoundryClient,
+	req Requester,
 	method string,
 	path any,
 	modifiers ...RequestModifier,
 ) ([]T, error) {
-	var result []T
-
-	currentPath := path
-	for i := 0; i < MaxPaginationPages; i++ {
-		paginated, err := SendRequestAndParseResult[CloudFoundryPaginatedResult[T]](
-			req, method, currentPath, modifiers...,
-		)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, paginated.Resources...)
-
-		if paginated.Pagination.Next != nil {
-			currentPath = AbsolutePath(paginated.Pagination.Next.Href)
-		} else {
-			break
-		}
-	}
+	return FetchAllPagesWithContext[T](context.Background(), req, method, path, modifiers...)
+}
 
-	return result, nil
+// FetchAllPagesWithContext is like FetchAllPages but uses the given context for every page request.
+// If the context is cancelled, no further pages are fetched and the context's error is returned.
+// If there are more than MaxPaginationPages pages, MaxPaginationPagesExceededErr is returned.
+func FetchAllPagesWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	method string,
+	path any,
+	modifiers ...RequestModifier,
+) ([]T, error) {
+	it := NewPageIterator[T](req, method, path, modifiers...)
+	return collectInstrumented(ctx, it, "cf.FetchAllPages", it.Collect)
 }
 
 // Get is a wrapper around SendRequest which automatically sets the method to GET
@@ -264,23 +454,52 @@ func FetchAllPages[T any](
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server
 func (req *CloudFoundryClient) Get(path string, modifiers ...RequestModifier) (*resty.Response, error) {
-	return req.SendRequest(resty.MethodGet, path, modifiers...)
+	return req.GetWithContext(context.Background(), path, modifiers...)
+}
+
+// GetWithContext is like Get but uses the given context
+func (req *CloudFoundryClient) GetWithContext(
+	ctx context.Context,
+	path string,
+	modifiers ...RequestModifier,
+) (*resty.Response, error) {
+	return req.SendRequestWithContext(ctx, resty.MethodGet, path, modifiers...)
 }
 
 // GetPaginated is a wrapper around FetchAllPages which automatically sets the method to GET
 // :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The resources from all pages
-func GetPaginated[T any](req *CloudFoundryClient, path string, modifiers ...RequestModifier) ([]T, error) {
-	return FetchAllPages[T](req, resty.MethodGet, path, modifiers...)
+func GetPaginated[T any](req Requester, path string, modifiers ...RequestModifier) ([]T, error) {
+	return GetPaginatedWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// GetPaginatedWithContext is like GetPaginated but uses the given context
+func GetPaginatedWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) ([]T, error) {
+	return FetchAllPagesWithContext[T](ctx, req, resty.MethodGet, path, modifiers...)
 }
 
 // GetResult is a wrapper around SendRequestAndParseResult which automatically sets the method to GET
 // :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server, parsed as the given type
-func GetResult[T any](req *CloudFoundryClient, path string, modifiers ...RequestModifier) (*T, error) {
-	return SendRequestAndParseResult[T](req, resty.MethodGet, path, modifiers...)
+func GetResult[T any](req Requester, path string, modifiers ...RequestModifier) (*T, error) {
+	return GetResultWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// GetResultWithContext is like GetResult but uses the given context
+func GetResultWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) (*T, error) {
+	return SendRequestAndParseResultWithContext[T](ctx, req, resty.MethodGet, path, modifiers...)
 }
 
 // Post is a wrapper around SendRequest which automatically sets the method to POST
@@ -288,23 +507,52 @@ func GetResult[T any](req *CloudFoundryClient, path string, modifiers ...Request
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server
 func (req *CloudFoundryClient) Post(path string, modifiers ...RequestModifier) (*resty.Response, error) {
-	return req.SendRequest(resty.MethodPost, path, modifiers...)
+	return req.PostWithContext(context.Background(), path, modifiers...)
+}
+
+// PostWithContext is like Post but uses the given context
+func (req *CloudFoundryClient) PostWithContext(
+	ctx context.Context,
+	path string,
+	modifiers ...RequestModifier,
+) (*resty.Response, error) {
+	return req.SendRequestWithContext(ctx, resty.MethodPost, path, modifiers...)
 }
 
 // PostResult is a wrapper around SendRequestAndParseResult which automatically sets the method to Post
 // :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server, parsed as the given type
-func PostResult[T any](req *CloudFoundryClient, path string, modifiers ...RequestModifier) (*T, error) {
-	return SendRequestAndParseResult[T](req, resty.MethodPost, path, modifiers...)
+func PostResult[T any](req Requester, path string, modifiers ...RequestModifier) (*T, error) {
+	return PostResultWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// PostResultWithContext is like PostResult but uses the given context
+func PostResultWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) (*T, error) {
+	return SendRequestAndParseResultWithContext[T](ctx, req, resty.MethodPost, path, modifiers...)
 }
 
 // PatchResult is a wrapper around SendRequestAndParseResult which automatically sets the method to Patch
 // :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server, parsed as the given type
-func PatchResult[T any](req *CloudFoundryClient, path string, modifiers ...RequestModifier) (*T, error) {
-	return SendRequestAndParseResult[T](req, resty.MethodPatch, path, modifiers...)
+func PatchResult[T any](req Requester, path string, modifiers ...RequestModifier) (*T, error) {
+	return PatchResultWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// PatchResultWithContext is like PatchResult but uses the given context
+func PatchResultWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) (*T, error) {
+	return SendRequestAndParseResultWithContext[T](ctx, req, resty.MethodPatch, path, modifiers...)
 }
 
 // DeleteAndExpectStatus is a wrapper around SendRequestAndParseResult which automatically sets the method to Delete
@@ -312,7 +560,17 @@ func PatchResult[T any](req *CloudFoundryClient, path string, modifiers ...Reque
 // :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
 // :return: The response from the server, parsed as the given type
 func (req *CloudFoundryClient) DeleteAndExpectStatus(path string, expectedStatus int, modifiers ...RequestModifier) error {
-	resp, err := req.SendRequest(resty.MethodDelete, path, modifiers...)
+	return req.DeleteAndExpectStatusWithContext(context.Background(), path, expectedStatus, modifiers...)
+}
+
+// DeleteAndExpectStatusWithContext is like DeleteAndExpectStatus but uses the given context
+func (req *CloudFoundryClient) DeleteAndExpectStatusWithContext(
+	ctx context.Context,
+	path string,
+	expectedStatus int,
+	modifiers ...RequestModifier,
+) error {
+	resp, err := req.SendRequestWithContext(ctx, resty.MethodDelete, path, modifiers...)
 	if err != nil {
 		return err
 	}
@@ -322,44 +580,25 @@ func (req *CloudFoundryClient) DeleteAndExpectStatus(path string, expectedStatus
 	return nil
 }
 
+// createMetadata returns the metadata of a request body for creating a resource
+// or nil if there are neither labels nor annotations
+func createMetadata(labels, annotations map[string]string) util.KV {
+	metadata := make(util.KV)
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
 // applyRequestModifiers applies the given modifiers to the request
 func applyRequestModifiers(r *resty.Request, modifiers ...RequestModifier) {
 	for _, c := range modifiers {
 		c(r)
 	}
 }
-
-// createParams creates the query parameters for a paginated request
-func createParams(ppo PaginationOptions) map[string]string {
-	m := make(map[string]string)
-	if ppo.PerPage > 0 {
-		m["per_page"] = strconv.Itoa(util.Clamp(ppo.PerPage, 1, MaxItemsPerPage))
-	} else {
-		m["per_page"] = strconv.Itoa(MaxItemsPerPage)
-	}
-	return m
-}
-
-// parseErrorResponse returns an error from the given body
-func parseErrorResponse(body []byte) error {
-	multiError := struct {
-		Errors []CloudFoundryError `json:"errors"`
-	}{}
-	if err := json.Unmarshal(body, &multiError); err != nil {
-		var singleError CloudFoundryError
-		if err = json.Unmarshal(body, &singleError); err != nil {
-			// if we can't parse the error, just return the raw body
-			return fmt.Errorf("api error. body: %s", body)
-		}
-		multiError = struct {
-			Errors []CloudFoundryError `json:"errors"`
-		}{
-			Errors: []CloudFoundryError{singleError},
-		}
-	}
-	var errors []string
-	for _, e := range multiError.Errors {
-		errors = append(errors, e.Error())
-	}
-	return fmt.Errorf("api error. errors: %s", strings.Join(errors, ", "))
-}
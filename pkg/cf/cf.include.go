@@ -0,0 +1,101 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+)
+
+// Include is a related resource which is returned in the same response using the include query parameter
+type Include string
+
+//goland:noinspection GoUnusedConst
+const (
+	IncludeUser         Include = "user"
+	IncludeSpace        Include = "space"
+	IncludeOrganization Include = "organization"
+)
+
+// CloudFoundryIncludedPaginatedResult is a paginated response which also contains the related resources
+// requested using the include query parameter
+type CloudFoundryIncludedPaginatedResult[T any] struct {
+	CloudFoundryPaginatedResult[T]
+	Included models.Included `json:"included"`
+}
+
+// CloudFoundryIncludedResult is a single resource which also contains the related resources
+// requested using the include query parameter
+type CloudFoundryIncludedResult[T any] struct {
+	Resource T
+	Included models.Included
+}
+
+// UnmarshalJSON decodes the resource and its included resources, which are returned in the same object
+func (r *CloudFoundryIncludedResult[T]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Resource); err != nil {
+		return err
+	}
+	var included struct {
+		Included models.Included `json:"included"`
+	}
+	if err := json.Unmarshal(data, &included); err != nil {
+		return err
+	}
+	r.Included = included.Included
+	return nil
+}
+
+// WithInclude is a request modifier that requests the given related resources to be included in the response
+func WithInclude(includes ...Include) RequestModifier {
+	return WithListQuery(NewListQuery().Include(includes...))
+}
+
+// GetPaginatedWithIncluded is like GetPaginated but also returns the related resources of all pages
+// which were requested using the include query parameter
+func GetPaginatedWithIncluded[T any](
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) ([]T, *models.Included, error) {
+	return GetPaginatedWithIncludedWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// GetPaginatedWithIncludedWithContext is like GetPaginatedWithIncluded but uses the given context
+func GetPaginatedWithIncludedWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) ([]T, *models.Included, error) {
+	it := NewPageIterator[T](req, resty.MethodGet, path, modifiers...)
+	resources, err := collectInstrumented(ctx, it, "cf.FetchAllPages", it.Collect)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resources, it.Included(), nil
+}
+
+// GetResultWithIncluded is like GetResult but also returns the related resources
+// which were requested using the include query parameter
+func GetResultWithIncluded[T any](
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) (*T, *models.Included, error) {
+	return GetResultWithIncludedWithContext[T](context.Background(), req, path, modifiers...)
+}
+
+// GetResultWithIncludedWithContext is like GetResultWithIncluded but uses the given context
+func GetResultWithIncludedWithContext[T any](
+	ctx context.Context,
+	req Requester,
+	path string,
+	modifiers ...RequestModifier,
+) (*T, *models.Included, error) {
+	result, err := GetResultWithContext[CloudFoundryIncludedResult[T]](ctx, req, path, modifiers...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &result.Resource, &result.Included, nil
+}
@@ -0,0 +1,212 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	InvalidConfigErr = errors.New("invalid config")
+)
+
+// getUnauthenticated sends an unauthenticated GET request to the given URL and parses the result as the given type.
+// If the request is sent on behalf of a client (see contextWithClient), it passes through the auth middleware
+// of the client and is logged by the client
+func getUnauthenticated[T any](ctx context.Context, cfg *CloudFoundryConfig, url string) (*T, error) {
+	client := clientFromContext(ctx)
+	r := &MiddlewareRequest{
+		Kind:   UnauthenticatedRequest,
+		Method: http.MethodGet,
+		URL:    url,
+	}
+	result := client.handleAuthRequest(ctx, r, func(ctx context.Context, r *MiddlewareRequest) MiddlewareResult {
+		httpClient, err := cfg.newRequestHTTPClient(ctx)
+		if err != nil {
+			return MiddlewareResult{Err: err}
+		}
+		request := httpClient.R().
+			SetContext(ctx).
+			SetResult(new(T))
+		applyRequestModifiers(request, r.Modifiers...)
+		start := time.Now()
+		resp, err := request.Execute(r.Method, r.URL)
+		duration := time.Since(start)
+		client.logRequest(ctx, "cf api request", request, resp, err, duration)
+		if err == nil && resp.StatusCode() >= 400 {
+			err = parseErrorResponse(resp)
+		}
+		return MiddlewareResult{Response: resp, Err: err, Attempts: 1, Duration: duration}
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.Response.Result().(*T), nil
+}
+
+// FetchRoot fetches the root document of the Cloud Controller at the APIEndpoint of the config
+func (cfg *CloudFoundryConfig) FetchRoot() (*models.Root, error) {
+	return cfg.FetchRootWithContext(context.Background())
+}
+
+// FetchRootWithContext is like FetchRoot but uses the given context
+func (cfg *CloudFoundryConfig) FetchRootWithContext(ctx context.Context) (*models.Root, error) {
+	if err := validateEndpoint("api_endpoint", cfg.APIEndpoint); err != nil {
+		return nil, err
+	}
+	return getUnauthenticated[models.Root](ctx, cfg, strings.TrimSuffix(cfg.APIEndpoint, "/")+"/")
+}
+
+// Discover fills in the AuthEndpoint and UAAEndpoint of the config (if they are empty)
+// using the links of the Cloud Controller root document. Only the APIEndpoint is required for this.
+// The fetched root document is returned
+func (cfg *CloudFoundryConfig) Discover() (*models.Root, error) {
+	return cfg.DiscoverWithContext(context.Background())
+}
+
+// DiscoverWithContext is like Discover but uses the given context
+func (cfg *CloudFoundryConfig) DiscoverWithContext(ctx context.Context) (*models.Root, error) {
+	root, err := cfg.FetchRootWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot discover endpoints: %w", err)
+	}
+	if cfg.UAAEndpoint == "" && root.Links.UAA != nil {
+		cfg.UAAEndpoint = root.Links.UAA.Href
+	}
+	if cfg.AuthEndpoint == "" {
+		switch {
+		case root.Links.Login != nil:
+			cfg.AuthEndpoint = root.Links.Login.Href
+		case root.Links.UAA != nil:
+			cfg.AuthEndpoint = root.Links.UAA.Href
+		}
+	}
+	return root, nil
+}
+
+// NewConfigFromAPI returns a config for the foundation at the given API endpoint.
+// The AuthEndpoint and UAAEndpoint are discovered from the Cloud Controller root document
+// and the OAuthClientID defaults to "cf" (the client used by the CF CLI).
+// Set the credentials on the returned config before creating a client
+func NewConfigFromAPI(apiEndpoint string) (*CloudFoundryConfig, error) {
+	return NewConfigFromAPIWithContext(context.Background(), apiEndpoint)
+}
+
+// NewConfigFromAPIWithContext is like NewConfigFromAPI but uses the given context
+func NewConfigFromAPIWithContext(ctx context.Context, apiEndpoint string) (*CloudFoundryConfig, error) {
+	cfg := &CloudFoundryConfig{
+		APIEndpoint:   strings.TrimSuffix(apiEndpoint, "/"),
+		OAuthClientID: "cf",
+	}
+	if _, err := cfg.DiscoverWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks if the config contains everything required to authenticate using its GrantType
+func (cfg *CloudFoundryConfig) Validate() error {
+	if err := validateEndpoint("api_endpoint", cfg.APIEndpoint); err != nil {
+		return err
+	}
+	if err := validateEndpoint("auth_endpoint", cfg.AuthEndpoint); err != nil {
+		return err
+	}
+	var missing []string
+	switch cfg.GrantType {
+	case "", PasswordGrant:
+		if cfg.Username == "" {
+			missing = append(missing, "username")
+		}
+		if cfg.Password == "" {
+			missing = append(missing, "password")
+		}
+	case ClientCredentialsGrant:
+		if cfg.OAuthClientID == "" {
+			missing = append(missing, "oauth_client_id")
+		}
+	case PasscodeGrant:
+		if cfg.Passcode == "" {
+			missing = append(missing, "passcode")
+		}
+	case JWTBearerGrant:
+		if cfg.JWTAssertion == "" {
+			missing = append(missing, "jwt_assertion")
+		}
+	default:
+		return fmt.Errorf("%w: %w: %s", InvalidConfigErr, UnsupportedGrantErr, cfg.GrantType)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s required for the %s grant",
+			InvalidConfigErr, strings.Join(missing, " and "), cfg.grantTypeOrDefault())
+	}
+	return nil
+}
+
+// grantTypeOrDefault returns the GrantType of the config or PasswordGrant if none is set
+func (cfg *CloudFoundryConfig) grantTypeOrDefault() GrantType {
+	if cfg.GrantType == "" {
+		return PasswordGrant
+	}
+	return cfg.GrantType
+}
+
+// validateEndpoint checks if the given endpoint is an absolute http(s) URL
+func validateEndpoint(name, endpoint string) error {
+	if endpoint == "" {
+		return fmt.Errorf("%w: %s is required", InvalidConfigErr, name)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", InvalidConfigErr, name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s must be an absolute http(s) URL, got %q", InvalidConfigErr, name, endpoint)
+	}
+	return nil
+}
+
+// GetRoot returns the root document of the Cloud Controller, which contains the links to all components
+// and the version of the API. The document is cached after the first request
+func (req *CloudFoundryClient) GetRoot() (*models.Root, error) {
+	return req.GetRootWithContext(context.Background())
+}
+
+// GetRootWithContext is like GetRoot but uses the given context
+func (req *CloudFoundryClient) GetRootWithContext(ctx context.Context) (*models.Root, error) {
+	req.infoMu.Lock()
+	defer req.infoMu.Unlock()
+	if req.root == nil {
+		root, err := req.config.FetchRootWithContext(contextWithClient(ctx, req))
+		if err != nil {
+			return nil, err
+		}
+		req.root = root
+	}
+	return req.root, nil
+}
+
+// GetInfo returns the information about the foundation (GET /v3/info).
+// The information is cached after the first request
+func (req *CloudFoundryClient) GetInfo() (*models.Info, error) {
+	return req.GetInfoWithContext(context.Background())
+}
+
+// GetInfoWithContext is like GetInfo but uses the given context
+func (req *CloudFoundryClient) GetInfoWithContext(ctx context.Context) (*models.Info, error) {
+	req.infoMu.Lock()
+	defer req.infoMu.Unlock()
+	if req.info == nil {
+		info, err := getUnauthenticated[models.Info](contextWithClient(ctx, req), req.config, req.config.resolveEndpointURL("/v3/info"))
+		if err != nil {
+			return nil, err
+		}
+		req.info = info
+	}
+	return req.info, nil
+}
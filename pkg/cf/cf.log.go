@@ -0,0 +1,149 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/darmiel/go-cf-client/internal/redact"
+	"github.com/go-resty/resty/v2"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LogVerbosity controls how much of every request is logged
+type LogVerbosity int
+
+//goland:noinspection GoUnusedConst
+const (
+	// LogRequests logs the method, URL, status code, duration and request id of every request
+	LogRequests LogVerbosity = iota
+	// LogHeaders additionally logs the request and response headers
+	LogHeaders
+	// LogBodies additionally logs the request and response bodies
+	LogBodies
+)
+
+// redacted replaces secrets in logged requests and responses
+const redacted = "[REDACTED]"
+
+// WithLogger is a client option that logs every request sent by the client to the given logger,
+// including token requests to the UAA. Successful requests are logged at debug level, failed requests at warn level.
+// Authorization headers, credentials and tokens are always redacted
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.logger = logger
+	}
+}
+
+// WithLogVerbosity is a client option that sets how much of every request is logged. Defaults to LogRequests
+func WithLogVerbosity(verbosity LogVerbosity) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.logVerbosity = verbosity
+	}
+}
+
+// clientContextKey is the context key of the client which sends a request on behalf of a config,
+// e.g. a token request of a TokenSource
+type clientContextKey struct{}
+
+// contextWithClient returns a context which carries the given client
+func contextWithClient(ctx context.Context, req *CloudFoundryClient) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, req)
+}
+
+// clientFromContext returns the client carried by the context or nil
+func clientFromContext(ctx context.Context) *CloudFoundryClient {
+	req, _ := ctx.Value(clientContextKey{}).(*CloudFoundryClient)
+	return req
+}
+
+// logRequest logs a finished request. It does nothing if the client has no logger (or is nil)
+func (req *CloudFoundryClient) logRequest(
+	ctx context.Context,
+	msg string,
+	r *resty.Request,
+	resp *resty.Response,
+	err error,
+	duration time.Duration,
+) {
+	if req == nil || req.logger == nil {
+		return
+	}
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("url", redactURL(r.URL)),
+		slog.Duration("duration", duration),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if resp != nil && resp.RawResponse != nil {
+		if resp.StatusCode() >= 400 {
+			level = slog.LevelWarn
+		}
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode()),
+			slog.String("request_id", resp.Header().Get("X-Vcap-Request-Id")),
+		)
+	}
+	if !req.logger.Enabled(ctx, level) {
+		return
+	}
+	if req.logVerbosity >= LogHeaders {
+		// the raw request also contains the headers added by resty, e.g. the Authorization header
+		header := r.Header
+		if r.RawRequest != nil {
+			header = r.RawRequest.Header
+		}
+		attrs = append(attrs, slog.Any("request_headers", redactHeaders(header)))
+		if resp != nil && resp.RawResponse != nil {
+			attrs = append(attrs, slog.Any("response_headers", redactHeaders(resp.Header())))
+		}
+	}
+	if req.logVerbosity >= LogBodies {
+		if body := requestBody(r); body != nil {
+			attrs = append(attrs, slog.String("request_body", redactBody(body)))
+		}
+		if resp != nil && len(resp.Body()) > 0 {
+			attrs = append(attrs, slog.String("response_body", redactBody(resp.Body())))
+		}
+	}
+	req.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// requestBody returns the body of the request as bytes
+func requestBody(r *resty.Request) []byte {
+	switch body := r.Body.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return body
+	case string:
+		return []byte(body)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+}
+
+// redactURL returns the URL with the values of all sensitive query parameters redacted
+func redactURL(rawURL string) string {
+	return redact.URL(rawURL, redacted)
+}
+
+// redactHeaders returns a copy of the headers with all sensitive headers redacted
+func redactHeaders(header http.Header) http.Header {
+	return redact.Header(header, redacted)
+}
+
+// redactBody returns the body with the values of all sensitive JSON fields redacted.
+// Bodies which are not JSON are returned as they are
+func redactBody(body []byte) string {
+	data, _ := redact.JSON(body, redacted)
+	return string(data)
+}
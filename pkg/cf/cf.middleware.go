@@ -0,0 +1,121 @@
+package cf
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"time"
+)
+
+// RequestKind is the kind of request which passes through the middleware of a client
+type RequestKind string
+
+//goland:noinspection GoUnusedConst
+const (
+	// APIRequest is an authenticated request to the Cloud Controller sent by SendRequest
+	APIRequest RequestKind = "api"
+
+	// TokenRequest is a token request to the UAA
+	TokenRequest RequestKind = "token"
+
+	// UnauthenticatedRequest is an unauthenticated request to the Cloud Controller,
+	// e.g. the root document fetched for the discovery of the endpoints
+	UnauthenticatedRequest RequestKind = "unauthenticated"
+)
+
+// MiddlewareRequest is a request which passes through the middleware of a client.
+// Middleware can modify the request before it calls the next handler
+type MiddlewareRequest struct {
+	// Kind is the kind of the request
+	Kind RequestKind
+
+	// Method is the HTTP method of the request
+	Method string
+
+	// URL is the full URL of the request. Middleware can rewrite it, e.g. to send the request through a gateway
+	URL string
+
+	// Modifiers are applied to every attempt of the request.
+	// Middleware can append modifiers, e.g. to set a correlation ID header
+	Modifiers []RequestModifier
+}
+
+// MiddlewareResult is the outcome of a request which passed through the middleware of a client
+type MiddlewareResult struct {
+	// Response is the last response of the request, or nil if no response was received
+	Response *resty.Response
+
+	// Err is the error of the request. Error responses are decoded,
+	// so Err is an *APIError for the Cloud Controller and a *TokenError for the UAA
+	Err error
+
+	// Attempts is the number of attempts made according to the retry policy.
+	// Token and unauthenticated requests are not retried
+	Attempts int
+
+	// Duration is the time it took to send the request including all attempts and token refreshes
+	Duration time.Duration
+}
+
+// RequestHandler sends a request and returns its result
+type RequestHandler func(ctx context.Context, r *MiddlewareRequest) MiddlewareResult
+
+// Middleware wraps the handler which sends a request. It can modify the request before calling next,
+// inspect or replace the result afterward, or return a result without calling next at all:
+//
+//	func(next cf.RequestHandler) cf.RequestHandler {
+//		return func(ctx context.Context, r *cf.MiddlewareRequest) cf.MiddlewareResult {
+//			r.Modifiers = append(r.Modifiers, func(r *resty.Request) {
+//				r.SetHeader("X-Correlation-ID", correlationID(ctx))
+//			})
+//			result := next(ctx, r)
+//			log.Println(r.Method, r.URL, result.Duration, result.Err)
+//			return result
+//		}
+//	}
+type Middleware func(next RequestHandler) RequestHandler
+
+// WithMiddleware is a client option that adds middleware around every request sent by SendRequest,
+// and therefore around every request of the client to the Cloud Controller.
+// The middleware wraps all attempts of a request. Middleware is called in the order it is added,
+// so the first middleware is the outermost one. Use WithAuthMiddleware to wrap the token requests
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithAuthMiddleware is like WithMiddleware but adds middleware around the requests of the client
+// which are not sent by SendRequest: the token requests to the UAA and the unauthenticated requests
+// to the Cloud Controller. Add the same middleware using both options to wrap all requests of the client
+func WithAuthMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.authMiddleware = append(c.authMiddleware, middleware...)
+	}
+}
+
+// handleRequest passes the request through the given middleware and finally sends it using send
+func handleRequest(
+	ctx context.Context,
+	middleware []Middleware,
+	r *MiddlewareRequest,
+	send RequestHandler,
+) MiddlewareResult {
+	handler := send
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler(ctx, r)
+}
+
+// handleAuthRequest passes the request through the auth middleware of the client and finally sends it using send.
+// The request is sent without middleware if the client is nil
+func (req *CloudFoundryClient) handleAuthRequest(
+	ctx context.Context,
+	r *MiddlewareRequest,
+	send RequestHandler,
+) MiddlewareResult {
+	if req == nil {
+		return send(ctx, r)
+	}
+	return handleRequest(ctx, req.authMiddleware, r, send)
+}
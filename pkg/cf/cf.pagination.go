@@ -0,0 +1,365 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+var (
+	MaxPaginationPagesExceededErr = errors.New("maximum number of pages exceeded")
+)
+
+// PageIterator lazily fetches the pages of a paginated response one after another.
+// A PageIterator is not safe for concurrent use.
+type PageIterator[T any] struct {
+	// MaxPages is the maximum number of pages to fetch. Defaults to MaxPaginationPages.
+	// If there are more pages, MaxPaginationPagesExceededErr is returned instead of an incomplete result
+	MaxPages int
+
+	req       Requester
+	method    string
+	modifiers []RequestModifier
+
+	// fetch returns the given page if the iterator was created using NewPageIteratorFunc
+	fetch func(ctx context.Context, page int) ([]T, bool, error)
+
+	// nextPath is the path (or for NewPageIteratorFunc the number) of the next page or nil if there are no more pages
+	nextPath   any
+	pagination PaginationInfo
+	fetched    int
+	included   models.Included
+}
+
+// NewPageIterator returns a PageIterator for the paginated resources at the given path
+// :param req: The requester to use
+// :param method: The HTTP method to use
+// :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
+// :param modifier: One or more optional modifiers that will be called with every page request before it is executed
+// :return: The iterator. No request is sent until the first page is fetched
+func NewPageIterator[T any](
+	req Requester,
+	method string,
+	path any,
+	modifiers ...RequestModifier,
+) *PageIterator[T] {
+	return &PageIterator[T]{
+		MaxPages:  MaxPaginationPages,
+		req:       req,
+		method:    method,
+		modifiers: modifiers,
+		nextPath:  path,
+	}
+}
+
+// GetPageIterator is a wrapper around NewPageIterator which automatically sets the method to GET
+func GetPageIterator[T any](req Requester, path string, modifiers ...RequestModifier) *PageIterator[T] {
+	return NewPageIterator[T](req, resty.MethodGet, path, modifiers...)
+}
+
+// NewPageIteratorFunc returns a PageIterator which fetches its pages using the given function instead of
+// sending requests, e.g. to serve pages from memory in tests. The function is called with the page number
+// (starting at 1) and returns the resources of the page and whether there are more pages.
+// TotalResults and TotalPages report the resources and pages fetched so far
+func NewPageIteratorFunc[T any](fetch func(ctx context.Context, page int) ([]T, bool, error)) *PageIterator[T] {
+	return &PageIterator[T]{
+		MaxPages: MaxPaginationPages,
+		fetch:    fetch,
+		nextPath: 1,
+	}
+}
+
+// newFailedPageIterator returns a PageIterator which returns the given error when the first page is fetched,
+// e.g. because the query of the list request is invalid
+func newFailedPageIterator[T any](err error) *PageIterator[T] {
+	return NewPageIteratorFunc(func(context.Context, int) ([]T, bool, error) {
+		return nil, false, err
+	})
+}
+
+// HasNext returns true if there is another page to fetch
+func (it *PageIterator[T]) HasNext() bool {
+	return it.nextPath != nil
+}
+
+// NextPage fetches the next page and returns its resources.
+// It returns MaxPaginationPagesExceededErr if there are more pages than MaxPages
+func (it *PageIterator[T]) NextPage(ctx context.Context) ([]T, error) {
+	if !it.HasNext() {
+		return nil, nil
+	}
+	if it.fetched >= it.MaxPages {
+		return nil, fmt.Errorf("%w: fetched %d of %d pages",
+			MaxPaginationPagesExceededErr, it.fetched, it.pagination.TotalPages)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if it.fetch != nil {
+		return it.nextFuncPage(ctx)
+	}
+	modifiers := it.modifiers
+	if path, ok := it.nextPath.(AbsolutePath); ok && it.fetched > 0 {
+		modifiers = it.pageModifiers(path)
+	}
+	paginated, err := SendRequestAndParseResultWithContext[CloudFoundryIncludedPaginatedResult[T]](
+		ctx, it.req, it.method, it.nextPath, modifiers...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	it.fetched++
+	it.pagination = paginated.Pagination
+	it.included.Append(paginated.Included)
+	if paginated.Pagination.Next != nil {
+		it.nextPath = AbsolutePath(paginated.Pagination.Next.Href)
+	} else {
+		it.nextPath = nil
+	}
+	return paginated.Resources, nil
+}
+
+// pageModifiers returns the modifiers for the request of a further page at the given path.
+// The links to further pages already contain the query parameters of the first request including page and per_page,
+// so query parameters set by the modifiers are dropped if the link contains them. Otherwise, they would be sent twice
+// and the Cloud Controller would use the values of the modifiers, e.g. the page of the first request
+func (it *PageIterator[T]) pageModifiers(path AbsolutePath) []RequestModifier {
+	link, err := url.Parse(string(path))
+	if err != nil {
+		return it.modifiers
+	}
+	query := link.Query()
+	return append(slices.Clone(it.modifiers), func(r *resty.Request) {
+		for key := range query {
+			r.QueryParam.Del(key)
+		}
+	})
+}
+
+// nextFuncPage fetches the next page using the fetch function of the iterator
+func (it *PageIterator[T]) nextFuncPage(ctx context.Context) ([]T, error) {
+	resources, more, err := it.fetch(ctx, it.fetched+1)
+	if err != nil {
+		return nil, err
+	}
+	it.fetched++
+	it.pagination.TotalPages = it.fetched
+	it.pagination.TotalResults += len(resources)
+	if more {
+		it.nextPath = it.fetched + 1
+	} else {
+		it.nextPath = nil
+	}
+	return resources, nil
+}
+
+// All returns an iterator over all resources of all pages. Pages are fetched lazily while iterating,
+// so breaking out of the loop stops fetching further pages.
+// If a page can't be fetched, the error is yielded and the iteration stops
+func (it *PageIterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for it.HasNext() {
+			page, err := it.NextPage(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, resource := range page {
+				if !yield(resource, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect fetches all remaining pages and returns their resources
+func (it *PageIterator[T]) Collect(ctx context.Context) ([]T, error) {
+	var result []T
+	for it.HasNext() {
+		page, err := it.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page...)
+	}
+	return result, nil
+}
+
+// CollectConcurrently fetches all remaining pages using up to the given number of concurrent requests
+// and returns their resources in the original order.
+// After the first page has been fetched (which reports the total number of pages), the URLs of the remaining pages
+// are built by setting the page query parameter of the next page's URL. If a page can't be fetched,
+// the requests for the other pages are cancelled and the error is returned.
+// Iterators created using NewPageIteratorFunc fetch their pages one after another
+func (it *PageIterator[T]) CollectConcurrently(ctx context.Context, workers int) ([]T, error) {
+	if it.fetch != nil {
+		return it.Collect(ctx)
+	}
+	var result []T
+	if it.fetched == 0 {
+		// the first page tells us how many pages there are
+		page, err := it.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result = page
+	}
+	if !it.HasNext() {
+		return result, nil
+	}
+
+	nextURL, err := url.Parse(string(it.nextPath.(AbsolutePath)))
+	if err != nil {
+		return nil, err
+	}
+	firstPage, err := strconv.Atoi(nextURL.Query().Get("page"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine page number of %s: %w", nextURL, err)
+	}
+	lastPage := it.pagination.TotalPages
+	if lastPage > it.MaxPages {
+		return nil, fmt.Errorf("%w: %d pages available, but only %d are allowed",
+			MaxPaginationPagesExceededErr, lastPage, it.MaxPages)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		pages    = make([][]T, lastPage-firstPage+1)
+		included = make([]models.Included, len(pages))
+		sem      = make(chan struct{}, max(workers, 1))
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		fetchErr error
+	)
+	for page := firstPage; page <= lastPage; page++ {
+		// wait for a free worker
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		query := nextURL.Query()
+		query.Set("page", strconv.Itoa(page))
+		pageURL := *nextURL
+		pageURL.RawQuery = query.Encode()
+
+		wg.Add(1)
+		go func(index int, path AbsolutePath) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			paginated, err := SendRequestAndParseResultWithContext[CloudFoundryIncludedPaginatedResult[T]](
+				ctx, it.req, it.method, path, it.pageModifiers(path)...,
+			)
+			if err != nil {
+				errOnce.Do(func() {
+					fetchErr = err
+					cancel()
+				})
+				return
+			}
+			pages[index] = paginated.Resources
+			included[index] = paginated.Included
+		}(page-firstPage, AbsolutePath(pageURL.String()))
+	}
+	wg.Wait()
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	// the parent context might have been cancelled before all pages were requested
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	it.fetched += len(pages)
+	it.nextPath = nil
+	for i, page := range pages {
+		result = append(result, page...)
+		it.included.Append(included[i])
+	}
+	return result, nil
+}
+
+// FetchAllPagesConcurrently is a wrapper around PageIterator.CollectConcurrently
+// which fetches all pages of a paginated response using up to the given number of concurrent requests
+// :param req: The requester to use
+// :param method: The HTTP method to use
+// :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
+// :param workers: The maximum number of concurrent requests
+// :param modifier: One or more optional modifiers that will be called with every page request before it is executed
+// :return: The resources from all pages in their original order
+func FetchAllPagesConcurrently[T any](
+	ctx context.Context,
+	req Requester,
+	method string,
+	path any,
+	workers int,
+	modifiers ...RequestModifier,
+) ([]T, error) {
+	it := NewPageIterator[T](req, method, path, modifiers...)
+	return collectInstrumented(ctx, it, "cf.FetchAllPagesConcurrently", func(ctx context.Context) ([]T, error) {
+		return it.CollectConcurrently(ctx, workers)
+	})
+}
+
+// collectInstrumented calls collect within a span and records the number of fetched pages if telemetry is enabled.
+// Iterators which don't use a CloudFoundryClient are not instrumented
+func collectInstrumented[T any](
+	ctx context.Context,
+	it *PageIterator[T],
+	name string,
+	collect func(ctx context.Context) ([]T, error),
+) ([]T, error) {
+	req, ok := it.req.(*CloudFoundryClient)
+	if !ok {
+		return collect(ctx)
+	}
+	t := &req.telemetry
+	resource := ResourceTypeKey.String(resourceType(req.config.resolveEndpointURL(it.nextPath)))
+	ctx, span := t.startSpan(ctx, name, trace.SpanKindInternal, resource)
+	resources, err := collect(ctx)
+	span.SetAttributes(
+		attribute.Int("cf.pagination.pages", it.fetched),
+		attribute.Int("cf.pagination.total_results", it.pagination.TotalResults),
+	)
+	t.recordPages(ctx, it.fetched, resource)
+	endSpan(span, err)
+	return resources, err
+}
+
+// TotalResults returns the total number of resources as reported by the last fetched page
+func (it *PageIterator[T]) TotalResults() int {
+	return it.pagination.TotalResults
+}
+
+// TotalPages returns the total number of pages as reported by the last fetched page
+func (it *PageIterator[T]) TotalPages() int {
+	return it.pagination.TotalPages
+}
+
+// Included returns the related resources of all pages fetched so far
+// which were requested using the include query parameter
+func (it *PageIterator[T]) Included() *models.Included {
+	return &it.included
+}
+
+// PagesFetched returns the number of pages fetched so far
+func (it *PageIterator[T]) PagesFetched() int {
+	return it.fetched
+}
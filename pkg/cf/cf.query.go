@@ -0,0 +1,244 @@
+package cf
+
+import (
+	"github.com/darmiel/go-cf-client/internal/util"
+	"github.com/darmiel/go-cf-client/pkg/labels"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrderBy is the attribute to sort list results by. Prepend with - to sort descending
+type OrderBy string
+
+// The constants are untyped, so they can also be used for the OrderBy fields of type string
+//
+//goland:noinspection GoUnusedConst
+const (
+	OrderByCreatedAtAsc  = "created_at"
+	OrderByCreatedAtDesc = "-created_at"
+	OrderByUpdatedAtAsc  = "updated_at"
+	OrderByUpdatedAtDesc = "-updated_at"
+	OrderByNameAsc       = "name"
+	OrderByNameDesc      = "-name"
+)
+
+// TimestampOperator is the relational operator of a TimestampFilter
+type TimestampOperator string
+
+//goland:noinspection GoUnusedConst
+const (
+	// TimestampEqual matches resources with exactly the given timestamp
+	TimestampEqual TimestampOperator = ""
+	// TimestampGreaterThan matches resources with a timestamp after the given timestamp
+	TimestampGreaterThan TimestampOperator = "gt"
+	// TimestampGreaterThanOrEqual matches resources with a timestamp at or after the given timestamp
+	TimestampGreaterThanOrEqual TimestampOperator = "gte"
+	// TimestampLessThan matches resources with a timestamp before the given timestamp
+	TimestampLessThan TimestampOperator = "lt"
+	// TimestampLessThanOrEqual matches resources with a timestamp at or before the given timestamp
+	TimestampLessThanOrEqual TimestampOperator = "lte"
+)
+
+// TimestampFilter filters resources by comparing one of their timestamps to Time
+type TimestampFilter struct {
+	Operator TimestampOperator
+	Time     time.Time
+}
+
+// TimestampFilterOptions filters resources by their creation or update time.
+// Multiple filters are combined, e.g. TimestampGreaterThan and TimestampLessThan to filter for a time range
+type TimestampFilterOptions struct {
+	// CreatedAts filters by the creation time of the resources
+	CreatedAts []TimestampFilter
+
+	// UpdatedAts filters by the last update time of the resources
+	UpdatedAts []TimestampFilter
+}
+
+// ListQuery is a builder for the query parameters of list requests.
+// Empty values are ignored, so the options of every list request can be passed to the builder as they are.
+// The zero value is an empty query which is ready to use.
+type ListQuery struct {
+	params map[string]string
+	err    error
+}
+
+// NewListQuery returns an empty ListQuery
+func NewListQuery() *ListQuery {
+	return &ListQuery{params: make(map[string]string)}
+}
+
+// Set sets the query parameter to the given value if it is not empty
+func (q *ListQuery) Set(key, value string) *ListQuery {
+	if value != "" {
+		if q.params == nil {
+			q.params = make(map[string]string)
+		}
+		q.params[key] = value
+	}
+	return q
+}
+
+// Filter sets the query parameter to the comma-delimited list of values if there is at least one non-empty value
+func (q *ListQuery) Filter(key string, values ...string) *ListQuery {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+	return q.Set(key, strings.Join(nonEmpty, ","))
+}
+
+// GUIDs filters by the GUIDs of the resources
+func (q *ListQuery) GUIDs(guids ...string) *ListQuery {
+	return q.Filter("guids", guids...)
+}
+
+// Names filters by the names of the resources
+func (q *ListQuery) Names(names ...string) *ListQuery {
+	return q.Filter("names", names...)
+}
+
+// OrderBy sorts the results by the given attribute
+func (q *ListQuery) OrderBy(orderBy OrderBy) *ListQuery {
+	return q.Set("order_by", string(orderBy))
+}
+
+// Page sets the page to fetch (starting at 1)
+func (q *ListQuery) Page(page int) *ListQuery {
+	if page > 0 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	return q
+}
+
+// PerPage sets the number of results per page.
+// The value is clamped to MaxItemsPerPage, values <= 0 request the maximum number of results per page
+func (q *ListQuery) PerPage(perPage int) *ListQuery {
+	if perPage > 0 {
+		q.Set("per_page", strconv.Itoa(util.Clamp(perPage, 1, MaxItemsPerPage)))
+	} else {
+		q.Set("per_page", strconv.Itoa(MaxItemsPerPage))
+	}
+	return q
+}
+
+// Pagination sets the page and number of results per page from the given options
+func (q *ListQuery) Pagination(options PaginationOptions) *ListQuery {
+	return q.Page(options.Page).PerPage(options.PerPage)
+}
+
+// Include requests the given related resources to be included in the response
+func (q *ListQuery) Include(includes ...Include) *ListQuery {
+	values := make([]string, len(includes))
+	for i, include := range includes {
+		values[i] = string(include)
+	}
+	return q.Filter("include", values...)
+}
+
+// LabelSelector filters by the given label selector.
+// Use Labels to filter by a selector built or parsed by the labels package
+func (q *ListQuery) LabelSelector(selector string) *ListQuery {
+	return q.Set("label_selector", selector)
+}
+
+// Labels filters by the requirements of the given selector in addition to a label selector set before.
+// A nil or empty selector is ignored. If the selector is invalid, its error is returned by Err
+func (q *ListQuery) Labels(selector *labels.Selector) *ListQuery {
+	if selector == nil {
+		return q
+	}
+	query, err := selector.Build()
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	if current := q.params["label_selector"]; current != "" && query != "" {
+		query = current + "," + query
+	}
+	return q.LabelSelector(query)
+}
+
+// CreatedAts filters by the creation time of the resources
+func (q *ListQuery) CreatedAts(filters ...TimestampFilter) *ListQuery {
+	return q.timestamps("created_ats", filters)
+}
+
+// UpdatedAts filters by the last update time of the resources
+func (q *ListQuery) UpdatedAts(filters ...TimestampFilter) *ListQuery {
+	return q.timestamps("updated_ats", filters)
+}
+
+// Timestamps sets the timestamp filters from the given options
+func (q *ListQuery) Timestamps(options TimestampFilterOptions) *ListQuery {
+	return q.CreatedAts(options.CreatedAts...).UpdatedAts(options.UpdatedAts...)
+}
+
+// timestamps sets the timestamp filters for the given key.
+// TimestampEqual filters are combined to a comma-delimited list, the other operators are set as key[operator].
+// The Cloud Controller accepts a single timestamp per operator, so if an operator is given several times,
+// the most restrictive timestamp is sent, e.g. the latest one for TimestampGreaterThan
+func (q *ListQuery) timestamps(key string, filters []TimestampFilter) *ListQuery {
+	var equal []string
+	bounds := make(map[TimestampOperator]time.Time)
+	for _, filter := range filters {
+		if filter.Operator == TimestampEqual {
+			equal = append(equal, filter.Time.UTC().Format(time.RFC3339))
+			continue
+		}
+		bound, ok := bounds[filter.Operator]
+		switch {
+		case !ok:
+			bound = filter.Time
+		case filter.Operator == TimestampGreaterThan || filter.Operator == TimestampGreaterThanOrEqual:
+			bound = latest(bound, filter.Time)
+		default:
+			bound = earliest(bound, filter.Time)
+		}
+		bounds[filter.Operator] = bound
+	}
+	for operator, bound := range bounds {
+		q.Set(key+"["+string(operator)+"]", bound.UTC().Format(time.RFC3339))
+	}
+	return q.Filter(key, equal...)
+}
+
+// latest returns the later of the two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// earliest returns the earlier of the two times
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// Err returns the first error of the values passed to the query, e.g. of an invalid label selector
+func (q *ListQuery) Err() error {
+	return q.err
+}
+
+// Params returns a copy of the query parameters
+func (q *ListQuery) Params() map[string]string {
+	params := make(map[string]string, len(q.params))
+	for key, value := range q.params {
+		params[key] = value
+	}
+	return params
+}
+
+// WithListQuery is a request modifier that sets the query parameters of the given ListQuery for a request
+func WithListQuery(q *ListQuery) RequestModifier {
+	return WithQueryParams(q.Params())
+}
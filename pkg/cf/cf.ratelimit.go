@@ -0,0 +1,261 @@
+package cf
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit configures the client-side rate limiting of requests to the Cloud Controller.
+// The zero value disables rate limiting.
+type RateLimit struct {
+	// RequestsPerSecond is the rate at which the request budget is refilled.
+	// A value <= 0 disables the token bucket
+	RequestsPerSecond float64
+
+	// Burst is the maximum request budget, i.e. the number of requests which can be sent at once
+	// after the client was idle. Defaults to 1
+	Burst int
+
+	// MaxInFlight is the maximum number of concurrent requests. A value <= 0 allows an unlimited number
+	MaxInFlight int
+
+	// AdaptToHeaders limits the budget to the X-RateLimit-Remaining header reported by the Cloud Controller
+	// and pauses all requests until X-RateLimit-Reset if the server reports that no requests are remaining
+	AdaptToHeaders bool
+}
+
+// RateLimitStats is a snapshot of the request budget of the client
+type RateLimitStats struct {
+	// Available is the number of requests which can currently be sent without waiting
+	Available float64
+
+	// Burst is the maximum request budget
+	Burst int
+
+	// InFlight is the number of requests which are currently being sent
+	InFlight int
+
+	// MaxInFlight is the maximum number of concurrent requests, 0 if unlimited
+	MaxInFlight int
+
+	// Waiting is the number of requests which are currently waiting for budget or a free slot
+	Waiting int
+
+	// ServerLimit is the last X-RateLimit-Limit reported by the Cloud Controller or -1 if unknown
+	ServerLimit int
+
+	// ServerRemaining is the last X-RateLimit-Remaining reported by the Cloud Controller or -1 if unknown
+	ServerRemaining int
+
+	// ServerReset is the last X-RateLimit-Reset reported by the Cloud Controller
+	ServerReset time.Time
+
+	// PausedUntil is the time until which all requests are paused because the server's rate limit is exhausted
+	PausedUntil time.Time
+}
+
+// WithRateLimit is a client option that limits the rate and concurrency of the requests sent by the client.
+// This applies to every attempt of SendRequest and therefore also to every page fetched by FetchAllPages.
+// Token requests to the UAA are not limited
+func WithRateLimit(limit RateLimit) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.rateLimiter = newRateLimiter(limit)
+	}
+}
+
+// RateLimitStats returns the current request budget of the client.
+// If rate limiting is disabled, the server values are unknown (-1) and the budget is reported as 0
+func (req *CloudFoundryClient) RateLimitStats() RateLimitStats {
+	if req.rateLimiter == nil {
+		return RateLimitStats{ServerLimit: -1, ServerRemaining: -1}
+	}
+	return req.rateLimiter.stats()
+}
+
+// rateLimiter is a token bucket combined with a semaphore for the number of concurrent requests
+type rateLimiter struct {
+	limit RateLimit
+
+	// slots is the semaphore for concurrent requests, nil if unlimited
+	slots chan struct{}
+
+	mu              sync.Mutex
+	tokens          float64
+	last            time.Time
+	waiting         int
+	serverLimit     int
+	serverRemaining int
+	serverReset     time.Time
+	pausedUntil     time.Time
+}
+
+// newRateLimiter returns a rate limiter for the given limit with a full budget
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	limit.Burst = max(limit.Burst, 1)
+	l := &rateLimiter{
+		limit:           limit,
+		tokens:          float64(limit.Burst),
+		last:            time.Now(),
+		serverLimit:     -1,
+		serverRemaining: -1,
+	}
+	if limit.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// acquire waits until a request can be sent according to the limit. The returned function must be called
+// after the request has finished to free its slot. A nil limiter never waits.
+// Only requests which are actually sent occupy a slot: the budget is waited for first, and a request which
+// has to wait for a free slot gives its budget back and takes it again once it got the slot,
+// so requests queued for a slot are not sent in a burst above the rate once slots become free
+func (l *rateLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.setWaiting(1)
+	defer l.setWaiting(-1)
+
+	for {
+		if err := l.waitForBudget(ctx); err != nil {
+			return nil, err
+		}
+		if l.slots == nil {
+			return func() {}, nil
+		}
+		release := func() { <-l.slots }
+		select {
+		case l.slots <- struct{}{}:
+			return release, nil
+		default:
+		}
+
+		l.unreserve()
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if l.reserve() == 0 {
+			return release, nil
+		}
+		// the budget was used up while waiting for the slot, so the slot is freed for requests with budget
+		release()
+	}
+}
+
+// waitForBudget waits until reserve takes a token from the bucket
+func (l *rateLimiter) waitForBudget(ctx context.Context) error {
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token from the bucket and returns 0, or returns the time to wait until a token is available
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+	l.refill(now)
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// unreserve gives a token taken by reserve back to the bucket
+func (l *rateLimiter) unreserve() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit.RequestsPerSecond > 0 {
+		l.refill(time.Now())
+		l.tokens = min(l.tokens+1, float64(l.limit.Burst))
+	}
+}
+
+// refill adds the tokens earned since the last refill to the bucket
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.limit.RequestsPerSecond, float64(l.limit.Burst))
+	l.last = now
+}
+
+// setWaiting adjusts the number of waiting requests by delta
+func (l *rateLimiter) setWaiting(delta int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waiting += delta
+}
+
+// observe updates the budget from the X-RateLimit headers of the response
+func (l *rateLimiter) observe(resp *resty.Response) {
+	if l == nil || resp == nil || resp.RawResponse == nil {
+		return
+	}
+	header := resp.Header()
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.serverRemaining = remaining
+	if limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit")); err == nil {
+		l.serverLimit = limit
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		l.serverReset = time.Unix(reset, 0)
+	}
+	if !l.limit.AdaptToHeaders {
+		return
+	}
+	if remaining <= 0 && l.serverReset.After(time.Now()) {
+		l.pausedUntil = l.serverReset
+	}
+	if l.limit.RequestsPerSecond > 0 {
+		l.refill(time.Now())
+		l.tokens = min(l.tokens, float64(remaining))
+	}
+}
+
+// stats returns a snapshot of the budget
+func (l *rateLimiter) stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := RateLimitStats{
+		Burst:           l.limit.Burst,
+		MaxInFlight:     max(l.limit.MaxInFlight, 0),
+		Waiting:         l.waiting,
+		ServerLimit:     l.serverLimit,
+		ServerRemaining: l.serverRemaining,
+		ServerReset:     l.serverReset,
+		PausedUntil:     l.pausedUntil,
+	}
+	if l.limit.RequestsPerSecond > 0 {
+		l.refill(time.Now())
+		stats.Available = l.tokens
+	} else {
+		stats.Available = float64(l.limit.Burst)
+	}
+	if l.slots != nil {
+		stats.InFlight = len(l.slots)
+	}
+	return stats
+}
@@ -0,0 +1,199 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures if and how failed requests are retried by SendRequest.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts (including the first one) for a request.
+	// A value <= 1 disables retries
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper bound for the time to wait between two attempts.
+	// This also caps the time taken from a Retry-After or X-RateLimit-Reset header
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff is multiplied with after every attempt
+	Multiplier float64
+
+	// Jitter is the fraction (0..1) of the backoff which is randomized to avoid thundering herds
+	Jitter float64
+
+	// RetryStatusCodes are the HTTP status codes which are considered retryable.
+	// Transport errors (e.g. connection resets or timeouts of an attempt) are always considered retryable,
+	// unless the context of the request is done
+	RetryStatusCodes []int
+
+	// RetryNonIdempotent also retries non-idempotent methods (POST, PATCH).
+	// Only enable this if duplicated writes are acceptable for you
+	RetryNonIdempotent bool
+
+	// OnAttempt is called after every attempt and reports its outcome
+	OnAttempt func(attempt RetryAttempt)
+}
+
+// RetryAttempt describes the outcome of a single attempt of a request
+type RetryAttempt struct {
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int
+
+	// Method is the HTTP method of the request
+	Method string
+
+	// URL is the URL of the request
+	URL string
+
+	// StatusCode is the HTTP status code of the response, or 0 if no response was received
+	StatusCode int
+
+	// Err is the transport error of the attempt, if any
+	Err error
+
+	// WillRetry is true if another attempt will be made
+	WillRetry bool
+
+	// Wait is the time to wait before the next attempt
+	Wait time.Duration
+}
+
+// DefaultRetryPolicy returns a retry policy which retries rate limited requests,
+// gateway errors and transport errors of idempotent requests up to 4 times
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy is a client option that sets the retry policy for all requests sent by the client
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// idempotentMethods are the HTTP methods which can safely be retried
+var idempotentMethods = []string{
+	resty.MethodGet,
+	resty.MethodHead,
+	resty.MethodOptions,
+	resty.MethodPut,
+	resty.MethodDelete,
+}
+
+// allowsRetry returns true if requests with the given method may be retried
+func (p RetryPolicy) allowsRetry(method string) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	return p.RetryNonIdempotent || slices.Contains(idempotentMethods, method)
+}
+
+// shouldRetry returns true if the given attempt result is retryable.
+// Errors are only retried if they are transport errors and the context of the request is not done
+func (p RetryPolicy) shouldRetry(ctx context.Context, resp *resty.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && isTransportError(err)
+	}
+	return slices.Contains(p.RetryStatusCodes, resp.StatusCode())
+}
+
+// isTransportError returns true if the error was caused by the connection to the server,
+// e.g. a connection reset, an incomplete response or a timeout of the attempt
+func isTransportError(err error) bool {
+	// every error of http.Client.Do is an *url.Error, which is a net.Error itself,
+	// so the error it wraps decides if the request failed because of the network
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// backoff returns the time to wait after the given attempt (starting at 1)
+// Retry-After and X-RateLimit-Reset headers of the response take precedence over the exponential backoff
+func (p RetryPolicy) backoff(attempt int, resp *resty.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+		return wait
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		wait -= wait * min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(wait)
+}
+
+// retryAfter returns the time to wait as requested by the server
+// using the Retry-After header (seconds or HTTP date) or, for exhausted rate limits, the X-RateLimit-Reset header
+func retryAfter(resp *resty.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	header := resp.Header()
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(max(seconds, 0)) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(time.Until(date), 0), true
+		}
+	}
+	if resp.StatusCode() == http.StatusTooManyRequests && header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+	}
+	return 0, false
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
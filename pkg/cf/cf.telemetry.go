@@ -0,0 +1,175 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// instrumentationName is the name of the tracer and meter of the client
+const instrumentationName = "github.com/darmiel/go-cf-client/pkg/cf"
+
+// ResourceTypeKey is the span and metric attribute containing the CF resource type of a request, e.g. "spaces"
+const ResourceTypeKey = attribute.Key("cf.resource.type")
+
+// telemetry contains the OpenTelemetry tracer and instruments of a client.
+// The tracer is nil if tracing is disabled, the instruments are nil if metrics are disabled
+type telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requestDuration metric.Float64Histogram
+	retries         metric.Int64Histogram
+	pages           metric.Int64Histogram
+	refreshDuration metric.Float64Histogram
+}
+
+// WithTracerProvider is a client option that creates spans for every request, paginated listing
+// and token refresh using the given provider. The trace context is propagated to the Cloud Controller and the UAA
+// using the global propagator (see otel.SetTextMapPropagator)
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.telemetry.tracer = provider.Tracer(instrumentationName)
+		c.telemetry.propagator = otel.GetTextMapPropagator()
+	}
+}
+
+// WithMeterProvider is a client option that records histograms of the request latency, the number of retries,
+// the number of fetched pages and the token refresh latency using the given provider
+func WithMeterProvider(provider metric.MeterProvider) ClientOption {
+	return func(c *CloudFoundryClient) {
+		meter := provider.Meter(instrumentationName)
+		var err error
+		if c.telemetry.requestDuration, err = meter.Float64Histogram("cf.client.request.duration",
+			metric.WithUnit("s"),
+			metric.WithDescription("Duration of requests to the Cloud Controller including retries"),
+		); err != nil {
+			otel.Handle(err)
+		}
+		if c.telemetry.retries, err = meter.Int64Histogram("cf.client.request.retries",
+			metric.WithUnit("{retry}"),
+			metric.WithDescription("Number of retries of requests to the Cloud Controller"),
+		); err != nil {
+			otel.Handle(err)
+		}
+		if c.telemetry.pages, err = meter.Int64Histogram("cf.client.pagination.pages",
+			metric.WithUnit("{page}"),
+			metric.WithDescription("Number of pages fetched by paginated listings"),
+		); err != nil {
+			otel.Handle(err)
+		}
+		if c.telemetry.refreshDuration, err = meter.Float64Histogram("cf.client.token.refresh.duration",
+			metric.WithUnit("s"),
+			metric.WithDescription("Duration of token refreshes"),
+		); err != nil {
+			otel.Handle(err)
+		}
+	}
+}
+
+// startSpan starts a span if tracing is enabled. Otherwise, the context is returned as it is with a no-op span
+func (t *telemetry) startSpan(
+	ctx context.Context,
+	name string,
+	kind trace.SpanKind,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	if t.tracer == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// inject propagates the trace context of ctx to the headers of the request
+func (t *telemetry) inject(ctx context.Context, r *resty.Request) {
+	if t.propagator == nil {
+		return
+	}
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+// recordRequest records the duration and retries of a finished request
+func (t *telemetry) recordRequest(ctx context.Context, duration time.Duration, retries int, attrs []attribute.KeyValue) {
+	if t.requestDuration != nil {
+		t.requestDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+	}
+	if t.retries != nil {
+		t.retries.Record(ctx, int64(retries), metric.WithAttributes(attrs...))
+	}
+}
+
+// recordPages records the number of pages fetched by a paginated listing
+func (t *telemetry) recordPages(ctx context.Context, pages int, attrs ...attribute.KeyValue) {
+	if t.pages != nil {
+		t.pages.Record(ctx, int64(pages), metric.WithAttributes(attrs...))
+	}
+}
+
+// recordRefresh records the duration of a token refresh
+func (t *telemetry) recordRefresh(ctx context.Context, duration time.Duration, err error) {
+	if t.refreshDuration != nil {
+		t.refreshDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(errorTypeAttr(err)...))
+	}
+}
+
+// endSpan records the error (if any) and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// requestAttributes returns the HTTP semantic attributes and the CF resource type of a request to the given URL.
+// The full URL is not included, as it would lead to a high cardinality of the metrics
+func requestAttributes(method, rawURL string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(method),
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return attrs
+	}
+	attrs = append(attrs, semconv.ServerAddress(u.Hostname()), ResourceTypeKey.String(resourceType(rawURL)))
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	return attrs
+}
+
+// resourceType returns the CF resource type of the given API URL, e.g. "spaces" for /v3/spaces/:guid/users
+func resourceType(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 2 && strings.HasPrefix(parts[0], "v") {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// errorTypeAttr returns the error.type attribute for the given error or no attribute if err is nil
+func errorTypeAttr(err error) []attribute.KeyValue {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return []attribute.KeyValue{semconv.ErrorTypeKey.String(strconv.Itoa(apiErr.StatusCode))}
+	}
+	return []attribute.KeyValue{semconv.ErrorTypeKey.String("_OTHER")}
+}
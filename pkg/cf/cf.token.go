@@ -0,0 +1,259 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"sync"
+	"time"
+)
+
+var (
+	NilTokenErr         = errors.New("token source returned no token")
+	UnsupportedGrantErr = errors.New("unsupported grant type")
+)
+
+// TokenSource is anything that can return a token which is used to authenticate requests against the Cloud Controller.
+// It is compatible with golang.org/x/oauth2.TokenSource, so every oauth2.TokenSource can be used as a TokenSource.
+//
+// The client caches the returned token and only calls Token again if the token is expired or a refresh is forced,
+// so implementations don't need to cache tokens themselves.
+type TokenSource interface {
+	Token() (*oauth2.Token, error)
+}
+
+// ContextTokenSource is a TokenSource which supports a context for fetching tokens.
+// If the client's TokenSource implements this interface, TokenWithContext is used instead of Token.
+type ContextTokenSource interface {
+	TokenSource
+	TokenWithContext(ctx context.Context) (*oauth2.Token, error)
+}
+
+// WithTokenSource is a client option that sets the token source used for authenticating all requests.
+// This replaces the grant configured by the GrantType of the config.
+func WithTokenSource(source TokenSource) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.tokenSource = source
+	}
+}
+
+// tokenFromSource fetches a token from the given source using the context if the source supports it
+func tokenFromSource(ctx context.Context, source TokenSource) (*oauth2.Token, error) {
+	var (
+		token *oauth2.Token
+		err   error
+	)
+	if s, ok := source.(ContextTokenSource); ok {
+		token, err = s.TokenWithContext(ctx)
+	} else {
+		token, err = source.Token()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, NilTokenErr
+	}
+	return token, nil
+}
+
+// Token returns the current token of the client and refreshes it before if it is expired.
+// This makes the client itself an oauth2.TokenSource which can be used to authenticate other requests.
+func (req *CloudFoundryClient) Token() (*oauth2.Token, error) {
+	token, err := req.validToken(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// toOAuth2Token converts the token info returned by the UAA to an oauth2.Token
+func (info *AuthTokenInfo) toOAuth2Token() *oauth2.Token {
+	token := &oauth2.Token{
+		AccessToken:  info.AccessToken,
+		TokenType:    info.TokenType,
+		RefreshToken: info.RefreshToken,
+	}
+	if info.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(info.ExpiresIn) * time.Second)
+	}
+	return token.WithExtra(map[string]any{
+		"scope": info.Scope,
+		"jti":   info.JTI,
+	})
+}
+
+// tokenInfoFromOAuth2Token converts an oauth2.Token to an AuthTokenInfo.
+// ExpiresIn is set to the remaining lifetime of the token in seconds
+func tokenInfoFromOAuth2Token(token *oauth2.Token) AuthTokenInfo {
+	info := AuthTokenInfo{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.Type(),
+		RefreshToken: token.RefreshToken,
+	}
+	if !token.Expiry.IsZero() {
+		info.ExpiresIn = max(int(time.Until(token.Expiry).Seconds()), 0)
+	}
+	if scope, ok := token.Extra("scope").(string); ok {
+		info.Scope = scope
+	}
+	if jti, ok := token.Extra("jti").(string); ok {
+		info.JTI = jti
+	}
+	return info
+}
+
+// uaaTokenSource is a TokenSource which requests tokens from the UAA.
+// The first token is requested using the configured grant, every further token is requested
+// using the refresh token of the previous token (if there is one).
+// If grant is nil, tokens can only be requested using the refresh token of an initial token
+type uaaTokenSource struct {
+	config *CloudFoundryConfig
+	grant  map[string]string
+
+	// reusableGrant is true if the grant can be used again if the refresh token is rejected
+	// this is not the case for one-time credentials like passcodes
+	reusableGrant bool
+
+	// grantUsed is true as soon as the grant has been used once
+	grantUsed bool
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// PasswordTokenSource returns a TokenSource which authenticates using the password grant
+// with the Username and Password of the config. Further tokens are requested using the refresh token.
+// Every call to Token requests a new token from the UAA.
+func (cfg *CloudFoundryConfig) PasswordTokenSource() TokenSource {
+	return &uaaTokenSource{
+		config:        cfg,
+		reusableGrant: true,
+		grant: cfg.withLoginHint(map[string]string{
+			"grant_type": string(PasswordGrant),
+			"scope":      "",
+			"username":   cfg.Username,
+			"password":   cfg.Password,
+		}),
+	}
+}
+
+// ClientCredentialsTokenSource returns a TokenSource which authenticates using the client_credentials grant
+// with the OAuthClientID and OAuthClientSecret of the config. As the UAA doesn't issue refresh tokens for this grant,
+// every further token is requested using the client credentials again.
+// Every call to Token requests a new token from the UAA.
+func (cfg *CloudFoundryConfig) ClientCredentialsTokenSource() TokenSource {
+	return &uaaTokenSource{
+		config:        cfg,
+		reusableGrant: true,
+		grant: map[string]string{
+			"grant_type": string(ClientCredentialsGrant),
+		},
+	}
+}
+
+// PasscodeTokenSource returns a TokenSource which authenticates using the password grant with the given
+// one-time passcode. As the passcode can only be used once, further tokens are requested using the refresh token.
+// Every call to Token requests a new token from the UAA.
+func (cfg *CloudFoundryConfig) PasscodeTokenSource(passcode string) TokenSource {
+	return &uaaTokenSource{
+		config: cfg,
+		grant: cfg.withLoginHint(map[string]string{
+			"grant_type": string(PasswordGrant),
+			"passcode":   passcode,
+		}),
+	}
+}
+
+// JWTBearerTokenSource returns a TokenSource which authenticates using the JWT bearer grant (RFC 7523)
+// by exchanging the given JWT issued by a trusted identity provider. Further tokens are requested using the refresh token.
+// Every call to Token requests a new token from the UAA.
+func (cfg *CloudFoundryConfig) JWTBearerTokenSource(assertion string) TokenSource {
+	return &uaaTokenSource{
+		config:        cfg,
+		reusableGrant: true,
+		grant: cfg.withLoginHint(map[string]string{
+			"grant_type": string(JWTBearerGrant),
+			"client_id":  cfg.OAuthClientID,
+			"assertion":  assertion,
+		}),
+	}
+}
+
+// PasscodeURL returns the URL of the login server's page where users can obtain a one-time passcode
+func (cfg *CloudFoundryConfig) PasscodeURL() string {
+	return cfg.AuthEndpoint + "/passcode"
+}
+
+// withLoginHint adds the login_hint for the Origin of the config to the given grant parameters
+func (cfg *CloudFoundryConfig) withLoginHint(params map[string]string) map[string]string {
+	if cfg.Origin != "" {
+		hint, _ := json.Marshal(map[string]string{"origin": cfg.Origin})
+		params["login_hint"] = string(hint)
+	}
+	return params
+}
+
+// TokenSource returns the TokenSource for the GrantType of the config
+func (cfg *CloudFoundryConfig) TokenSource() (TokenSource, error) {
+	switch cfg.GrantType {
+	case "", PasswordGrant:
+		return cfg.PasswordTokenSource(), nil
+	case ClientCredentialsGrant:
+		return cfg.ClientCredentialsTokenSource(), nil
+	case PasscodeGrant:
+		return cfg.PasscodeTokenSource(cfg.Passcode), nil
+	case JWTBearerGrant:
+		return cfg.JWTBearerTokenSource(cfg.JWTAssertion), nil
+	}
+	return nil, fmt.Errorf("%w: %s", UnsupportedGrantErr, cfg.GrantType)
+}
+
+// Token requests a new token from the UAA
+func (s *uaaTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext is like Token but uses the given context for the token request.
+// If the refresh token is rejected by the UAA, the token is requested using the original grant again if possible,
+// otherwise an error wrapping ReauthenticationRequiredErr is returned
+func (s *uaaTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && s.token.RefreshToken != "" {
+		info, err := s.config.requestToken(ctx, map[string]string{
+			"grant_type":    "refresh_token",
+			"refresh_token": s.token.RefreshToken,
+		})
+		if err == nil {
+			s.token = info.toOAuth2Token()
+			return s.token, nil
+		}
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) || !tokenErr.isRejectedGrant() {
+			return nil, err
+		}
+		// the refresh token is expired or has been revoked
+		s.token = nil
+		if !s.reusableGrant {
+			return nil, fmt.Errorf("%w: refresh token rejected: %w", ReauthenticationRequiredErr, err)
+		}
+	}
+
+	if s.grant == nil {
+		return nil, fmt.Errorf("%w: no credentials to request a new token", ReauthenticationRequiredErr)
+	}
+	if s.grantUsed && !s.reusableGrant {
+		return nil, fmt.Errorf("%w: the %s grant can only be used once", ReauthenticationRequiredErr, s.grant["grant_type"])
+	}
+	s.grantUsed = true
+	info, err := s.config.requestToken(ctx, s.grant)
+	if err != nil {
+		return nil, err
+	}
+	s.token = info.toOAuth2Token()
+	return s.token, nil
+}
@@ -0,0 +1,266 @@
+package cf
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+var (
+	// InvalidTransportErr is returned by NewClient if the transport options cannot be applied,
+	// e.g. because a CA bundle contains no certificate or the proxy URL is invalid
+	InvalidTransportErr = errors.New("invalid transport options")
+
+	// proxySchemes are the supported schemes of proxy URLs
+	proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+)
+
+// transportOptions configure the HTTP client which is used for the requests to the Cloud Controller and the UAA
+type transportOptions struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+
+	// caCertificates are PEM encoded CA certificates which are trusted in addition to the system pool
+	caCertificates [][]byte
+	caFiles        []string
+
+	certificates     []tls.Certificate
+	certificateFiles []certificateFile
+
+	proxyURL    string
+	timeout     time.Duration
+	dialTimeout time.Duration
+	userAgent   string
+
+	// wrappers wrap the transport after all other options have been applied
+	wrappers []func(next http.RoundTripper) http.RoundTripper
+}
+
+// certificateFile is a client certificate and its private key, stored in PEM encoded files
+type certificateFile struct {
+	certFile string
+	keyFile  string
+}
+
+// WithHTTPClient is a client option that sends all requests using a copy of the given http.Client.
+// Its transport is still configured by the other transport options, which requires an *http.Transport
+// if TLS, proxy or dial options are used
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.httpClient = client
+	}
+}
+
+// WithTransport is a client option that sends all requests using the given transport.
+// The transport is cloned before the TLS, proxy and dial options are applied, which requires an *http.Transport
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.transport = transport
+	}
+}
+
+// WithCACertificates is a client option that trusts the given PEM encoded CA certificates
+// in addition to the system certificates, e.g. the CA of an internal foundation
+func WithCACertificates(pemCerts []byte) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.caCertificates = append(c.transportOptions.caCertificates, pemCerts)
+	}
+}
+
+// WithCAFile is like WithCACertificates but reads the PEM encoded CA certificates from the given file
+func WithCAFile(path string) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.caFiles = append(c.transportOptions.caFiles, path)
+	}
+}
+
+// WithClientCertificate is a client option that presents the given certificate to servers
+// which require client certificates (mTLS)
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.certificates = append(c.transportOptions.certificates, cert)
+	}
+}
+
+// WithClientCertificateFile is like WithClientCertificate but loads the certificate and its private key
+// from the given PEM encoded files
+func WithClientCertificateFile(certFile, keyFile string) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.certificateFiles = append(
+			c.transportOptions.certificateFiles,
+			certificateFile{certFile: certFile, keyFile: keyFile},
+		)
+	}
+}
+
+// WithProxy is a client option that sends all requests through the proxy with the given URL,
+// e.g. "http://proxy:3128" or "socks5://proxy:1080". Supported schemes are http, https, socks5 and socks5h.
+// By default, the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func WithProxy(proxyURL string) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.proxyURL = proxyURL
+	}
+}
+
+// WithTimeout is a client option that limits the time of a single request attempt including reading the response.
+// Retries (see WithRetryPolicy) get a new timeout. By default, requests are only limited by their context
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.timeout = timeout
+	}
+}
+
+// WithDialTimeout is a client option that limits the time to establish a connection. Defaults to 30 seconds
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.dialTimeout = timeout
+	}
+}
+
+// WithUserAgent is a client option that sets the User-Agent header of all requests
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.userAgent = userAgent
+	}
+}
+
+// WithTransportWrapper is a client option that wraps the HTTP transport of the client,
+// e.g. to record or inspect the raw HTTP traffic. The wrapper applies to all requests of the client
+// including token requests to the UAA and the discovery of the endpoints.
+// Multiple wrappers are applied in the given order, so the last wrapper is the outermost one
+func WithTransportWrapper(wrap func(next http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.transportOptions.wrappers = append(c.transportOptions.wrappers, wrap)
+	}
+}
+
+// newHTTPClient returns a new resty client configured according to the config and the given options.
+// The same client is used for the requests to the Cloud Controller and the UAA
+func (cfg *CloudFoundryConfig) newHTTPClient(options transportOptions) (*resty.Client, error) {
+	var client *resty.Client
+	if options.httpClient != nil {
+		// resty modifies the given client, so it is copied
+		httpClient := *options.httpClient
+		client = resty.NewWithClient(&httpClient)
+	} else {
+		client = resty.New()
+	}
+	if options.transport != nil {
+		client.SetTransport(options.transport)
+	}
+	if cfg.SkipSSLValidation || options.configuresTransport() {
+		transport, ok := client.GetClient().Transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("%w: TLS, proxy and dial options require an *http.Transport, got %T",
+				InvalidTransportErr, client.GetClient().Transport)
+		}
+		// the transport might be shared with other clients, so it is cloned before it is modified
+		transport = transport.Clone()
+		if err := cfg.configureTransport(transport, options); err != nil {
+			return nil, err
+		}
+		client.SetTransport(transport)
+	}
+	if options.timeout > 0 {
+		client.SetTimeout(options.timeout)
+	}
+	if options.userAgent != "" {
+		client.SetHeader("User-Agent", options.userAgent)
+	}
+
+	transport := client.GetClient().Transport
+	for _, wrap := range options.wrappers {
+		transport = wrap(transport)
+	}
+	return client.SetTransport(transport), nil
+}
+
+// configuresTransport returns true if the options modify the *http.Transport of the client
+func (options transportOptions) configuresTransport() bool {
+	return len(options.caCertificates) > 0 || len(options.caFiles) > 0 ||
+		len(options.certificates) > 0 || len(options.certificateFiles) > 0 ||
+		options.proxyURL != "" || options.dialTimeout > 0
+}
+
+// configureTransport applies the TLS, proxy and dial options to the transport
+func (cfg *CloudFoundryConfig) configureTransport(transport *http.Transport, options transportOptions) error {
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+	if cfg.SkipSSLValidation {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	caCertificates := slices.Clone(options.caCertificates)
+	for _, path := range options.caFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%w: cannot read CA file: %w", InvalidTransportErr, err)
+		}
+		caCertificates = append(caCertificates, data)
+	}
+	if len(caCertificates) > 0 {
+		pool := tlsConfig.RootCAs
+		if pool == nil {
+			var err error
+			if pool, err = x509.SystemCertPool(); err != nil {
+				pool = x509.NewCertPool()
+			}
+		} else {
+			pool = pool.Clone()
+		}
+		for _, pemCerts := range caCertificates {
+			if !pool.AppendCertsFromPEM(pemCerts) {
+				return fmt.Errorf("%w: CA bundle contains no PEM encoded certificate", InvalidTransportErr)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	tlsConfig.Certificates = append(slices.Clone(tlsConfig.Certificates), options.certificates...)
+	for _, file := range options.certificateFiles {
+		cert, err := tls.LoadX509KeyPair(file.certFile, file.keyFile)
+		if err != nil {
+			return fmt.Errorf("%w: cannot load client certificate: %w", InvalidTransportErr, err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if options.proxyURL != "" {
+		proxy, err := url.Parse(options.proxyURL)
+		if err != nil {
+			return fmt.Errorf("%w: invalid proxy URL: %w", InvalidTransportErr, err)
+		}
+		if !slices.Contains(proxySchemes, proxy.Scheme) || proxy.Host == "" {
+			return fmt.Errorf("%w: invalid proxy URL %q, expected one of the schemes %v and a host",
+				InvalidTransportErr, options.proxyURL, proxySchemes)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if options.dialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: options.dialTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	return nil
+}
+
+// newRequestHTTPClient returns the resty client for a request of the config.
+// If the request is sent on behalf of a client (see contextWithClient), the HTTP client of the client is reused,
+// otherwise a new HTTP client is created
+func (cfg *CloudFoundryConfig) newRequestHTTPClient(ctx context.Context) (*resty.Client, error) {
+	if req := clientFromContext(ctx); req != nil && req.httpClient != nil {
+		return req.httpClient, nil
+	}
+	return cfg.newHTTPClient(transportOptions{})
+}
@@ -0,0 +1,186 @@
+package cf_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"golang.org/x/oauth2"
+)
+
+// writeCLIConfig writes the CLI config to a temporary CF_HOME and returns the path of the config
+func writeCLIConfig(t *testing.T, config map[string]any) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("CF_HOME", home)
+	path := filepath.Join(home, ".cf", "config.json")
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readCLIConfig reads the raw CLI config
+func readCLIConfig(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("cannot parse config %s: %v", data, err)
+	}
+	return config
+}
+
+// assertOnlyConfig fails if the directory of the config contains other files, e.g. left over temporary files
+func assertOnlyConfig(t *testing.T, path string) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(path) {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("config directory contains %v, want only %s", names, filepath.Base(path))
+	}
+}
+
+func TestCLIConfigSaveKeepsOtherFields(t *testing.T) {
+	path := writeCLIConfig(t, map[string]any{
+		"ConfigVersion": 3,
+		"Target":        "https://api.example.com",
+		"AccessToken":   "bearer old",
+		"RefreshToken":  "old-refresh",
+		"ColorEnabled":  "true",
+	})
+	config, err := cf.LoadCLIConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.SetToken(&oauth2.Token{TokenType: "Bearer", AccessToken: "new", RefreshToken: "new-refresh"})
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := readCLIConfig(t, path)
+	want := map[string]any{
+		"ConfigVersion": float64(3),
+		"Target":        "https://api.example.com",
+		"AccessToken":   "bearer new",
+		"RefreshToken":  "new-refresh",
+		"ColorEnabled":  "true",
+	}
+	for key, value := range want {
+		if saved[key] != value {
+			t.Errorf("%s = %v, want %v", key, saved[key], value)
+		}
+	}
+	assertOnlyConfig(t, path)
+}
+
+func TestCLIConfigSaveKeepsFileMode(t *testing.T) {
+	for _, mode := range []os.FileMode{0o600, 0o640, 0o644} {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := writeCLIConfig(t, map[string]any{"AccessToken": "bearer old"})
+			if err := os.Chmod(path, mode); err != nil {
+				t.Fatal(err)
+			}
+			config, err := cf.LoadCLIConfig("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.SetToken(&oauth2.Token{TokenType: "Bearer", AccessToken: "new"})
+			if err := config.Save(); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != mode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), mode)
+			}
+		})
+	}
+}
+
+func TestCLIConfigSaveIsAtomic(t *testing.T) {
+	path := writeCLIConfig(t, map[string]any{"Target": "https://api.example.com", "AccessToken": "bearer token"})
+	config, err := cf.LoadCLIConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			config.SetToken(&oauth2.Token{AccessToken: "token", RefreshToken: "refresh"})
+			if err := config.Save(); err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		close(done)
+	}()
+	// readers never see a partially written config
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			assertOnlyConfig(t, path)
+			return
+		default:
+			readCLIConfig(t, path)
+		}
+	}
+}
+
+func TestCLIConfigPersistsRefreshedTokens(t *testing.T) {
+	server := newServer(t, 1)
+	refreshToken := newClient(t, server).GetTokenInfo().RefreshToken
+	path := writeCLIConfig(t, map[string]any{
+		"Target":                server.URL,
+		"AuthorizationEndpoint": server.URL,
+		"UaaEndpoint":           server.URL,
+		"UAAOAuthClient":        cftest.DefaultClientID,
+		"RefreshToken":          refreshToken,
+	})
+	config, err := cf.LoadCLIConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.PersistTokens = true
+
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	token := client.GetTokenInfo()
+	saved := readCLIConfig(t, path)
+	if saved["AccessToken"] != "bearer "+token.AccessToken || saved["RefreshToken"] != token.RefreshToken {
+		t.Errorf("saved tokens = %v / %v, want the refreshed tokens", saved["AccessToken"], saved["RefreshToken"])
+	}
+	assertOnlyConfig(t, path)
+}
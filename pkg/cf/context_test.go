@@ -0,0 +1,67 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+func TestDeadlineStopsListingPages(t *testing.T) {
+	var requests atomic.Int32
+	server := newServer(t, 10)
+	client := newClient(t, server, cf.WithTransportWrapper(countRequests("/v3/spaces", &requests)))
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	spaces, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{
+		PaginationOptions: cf.PaginationOptions{Page: 1, PerPage: 2},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("spaces = %v, err = %v, want context.DeadlineExceeded", names(spaces), err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("listing took %v, want it to stop at the deadline", elapsed)
+	}
+	// no further pages are requested once the page in flight at the deadline is cancelled
+	if got := requests.Load(); got == 0 || got >= 5 {
+		t.Errorf("%d of 5 pages were requested, want the listing to stop early", got)
+	}
+}
+
+func TestDeadlineStopsTokenRefresh(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server)
+	server.InjectFault(cftest.Fault{Path: "/oauth/token", Delay: 10 * time.Second})
+
+	// the rejected token has to be refreshed before the request is sent again
+	server.ExpireTokens()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v, want it to stop at the deadline", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := client.RefreshTokenWithContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	// the cancelled refreshes don't block further requests
+	server.ClearFaults()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertGrants(t, server, "password", "refresh_token")
+}
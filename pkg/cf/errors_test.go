@@ -0,0 +1,119 @@
+package cf_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+func TestErrorsMatchSentinels(t *testing.T) {
+	sentinels := []error{cf.NotFoundErr, cf.UniquenessErr, cf.ForbiddenErr, cf.UnauthorizedErr}
+	tests := []struct {
+		name   string
+		fault  *cftest.Fault
+		call   func(client *cf.CloudFoundryClient) error
+		status int
+		code   int
+		want   error
+	}{
+		{
+			name: "not found",
+			call: func(client *cf.CloudFoundryClient) error {
+				_, err := client.GetSpace("missing")
+				return err
+			},
+			status: http.StatusNotFound,
+			code:   cf.ResourceNotFoundErrorCode,
+			want:   cf.NotFoundErr,
+		},
+		{
+			name: "not found without errors",
+			fault: &cftest.Fault{
+				Method: http.MethodGet, Path: "/v3/spaces/*", Status: http.StatusNotFound, Body: []byte("not found"),
+			},
+			call: func(client *cf.CloudFoundryClient) error {
+				_, err := client.GetSpace("missing")
+				return err
+			},
+			status: http.StatusNotFound,
+			want:   cf.NotFoundErr,
+		},
+		{
+			name: "uniqueness",
+			call: func(client *cf.CloudFoundryClient) error {
+				_, err := client.CreateSpace("space-1", orgGUID, cf.CreateSpaceOptions{})
+				return err
+			},
+			status: http.StatusUnprocessableEntity,
+			code:   cf.UniquenessErrorCode,
+			want:   cf.UniquenessErr,
+		},
+		{
+			name: "unprocessable entity",
+			call: func(client *cf.CloudFoundryClient) error {
+				_, err := client.CreateSpace("", orgGUID, cf.CreateSpaceOptions{})
+				return err
+			},
+			status: http.StatusUnprocessableEntity,
+			code:   10008,
+		},
+		{
+			name: "forbidden",
+			fault: &cftest.Fault{
+				Method: http.MethodPost, Path: "/v3/spaces", Status: http.StatusForbidden,
+				Errors: []cf.CloudFoundryError{{Code: cf.NotAuthorizedErrorCode, Title: "CF-NotAuthorized"}},
+			},
+			call: func(client *cf.CloudFoundryClient) error {
+				_, err := client.CreateSpace("space-2", orgGUID, cf.CreateSpaceOptions{})
+				return err
+			},
+			status: http.StatusForbidden,
+			code:   cf.NotAuthorizedErrorCode,
+			want:   cf.ForbiddenErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t, 1)
+			client := newClient(t, server)
+			if tt.fault != nil {
+				server.InjectFault(*tt.fault)
+			}
+			err := tt.call(client)
+
+			var apiErr *cf.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Method == "" || apiErr.URL == "" {
+				t.Errorf("api error = %+v, want status %d with the request", apiErr, tt.status)
+			}
+			var cfErr cf.CloudFoundryError
+			if tt.code != 0 && (!errors.As(err, &cfErr) || cfErr.Code != tt.code || !apiErr.HasCode(tt.code)) {
+				t.Errorf("cf error = %+v, want code %d", cfErr, tt.code)
+			}
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(err, %v) = %v, want %v", sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server)
+
+	_, err := client.GetSpace("missing")
+	if !cf.IsNotFound(err) || cf.IsUniqueness(err) || cf.IsForbidden(err) || cf.IsUnauthorized(err) {
+		t.Errorf("helpers do not match the not found error %v", err)
+	}
+	_, err = client.CreateSpace("space-1", orgGUID, cf.CreateSpaceOptions{})
+	if !cf.IsUniqueness(err) || cf.IsNotFound(err) {
+		t.Errorf("helpers do not match the uniqueness error %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package cf_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+const orgGUID = "3f1b2c4d-0000-4000-8000-000000000001"
+
+// newServer starts a fake Cloud Controller with an organization and the given number of spaces
+// named space-1, space-2, ...
+func newServer(t *testing.T, spaces int, options ...cftest.Option) *cftest.Server {
+	t.Helper()
+	fixtures := cftest.Fixtures{
+		Organizations: []models.Organization{{Guid: orgGUID, Name: "org"}},
+	}
+	for i := 1; i <= spaces; i++ {
+		space := models.Space{Name: fmt.Sprintf("space-%d", i)}
+		space.Relationships.Organization.Data.Guid = orgGUID
+		fixtures.Spaces = append(fixtures.Spaces, space)
+	}
+	server := cftest.NewServer(append(options, cftest.WithFixtures(fixtures))...)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newClient returns a client which is authenticated against the server
+func newClient(t *testing.T, server *cftest.Server, options ...cf.ClientOption) *cf.CloudFoundryClient {
+	t.Helper()
+	client, err := server.NewClient(options...)
+	if err != nil {
+		t.Fatalf("cannot create client: %v", err)
+	}
+	return client
+}
+
+// names returns the names of the spaces
+func names(spaces []models.Space) []string {
+	result := make([]string, len(spaces))
+	for i, space := range spaces {
+		result[i] = space.Name
+	}
+	return result
+}
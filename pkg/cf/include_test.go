@@ -0,0 +1,134 @@
+package cf_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+// newRole returns a role fixture of the user in the space or, if spaceGUID is empty, in the organization orgGUID
+func newRole(roleType, userGUID, spaceGUID string) models.Role {
+	role := models.Role{Type: roleType}
+	role.Relationships.User.Data.Guid = userGUID
+	if spaceGUID != "" {
+		role.Relationships.Space.Data.Guid = spaceGUID
+	} else {
+		role.Relationships.Organization.Data.Guid = orgGUID
+	}
+	return role
+}
+
+// newRoleServer starts a fake Cloud Controller with two spaces and two users,
+// where every user has a role in the organization and in one or both spaces
+func newRoleServer(t *testing.T) *cftest.Server {
+	t.Helper()
+	fixtures := cftest.Fixtures{
+		Organizations: []models.Organization{{Guid: orgGUID, Name: "org"}},
+		Users:         []models.User{{Guid: "user-1", Username: "alice"}, {Guid: "user-2", Username: "bob"}},
+		Roles: []models.Role{
+			newRole("space_developer", "user-1", "space-guid-1"),
+			newRole("space_manager", "user-1", "space-guid-2"),
+			newRole("space_developer", "user-2", "space-guid-1"),
+			newRole("organization_user", "user-2", ""),
+		},
+	}
+	for i := 1; i <= 2; i++ {
+		space := models.Space{Guid: fmt.Sprintf("space-guid-%d", i), Name: fmt.Sprintf("space-%d", i)}
+		space.Relationships.Organization.Data.Guid = orgGUID
+		fixtures.Spaces = append(fixtures.Spaces, space)
+	}
+	server := cftest.NewServer(cftest.WithFixtures(fixtures))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// describeRoles returns a description of the roles including the names of their users and spaces
+func describeRoles(roles []models.EnrichedRole) []string {
+	result := make([]string, len(roles))
+	for i, role := range roles {
+		result[i] = fmt.Sprintf("%s:%s@%s/%s", role.Type, role.Username(), role.SpaceName(), role.OrganizationName())
+	}
+	return result
+}
+
+func TestIncludedResourcesAreMergedAcrossPages(t *testing.T) {
+	server := newRoleServer(t)
+	var queries []string
+	client := newClient(t, server, cf.WithMiddleware(recordQueries(&queries)))
+
+	// every page contains a single role, so the same user and space are included in several pages
+	it := client.ListRoleIterator(cf.ListRoleOptions{
+		PaginationOptions: cf.PaginationOptions{Page: 1, PerPage: 1},
+		Include:           []cf.Include{cf.IncludeUser, cf.IncludeSpace},
+	})
+	roles, err := it.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 4 {
+		t.Errorf("fetched %d pages, want 4: %v", len(queries), queries)
+	}
+	for _, query := range queries {
+		if values, _ := url.ParseQuery(query); values.Get("include") != "user,space" {
+			t.Errorf("query %q doesn't include the user and space", query)
+		}
+	}
+
+	included := it.Included()
+	var users, spaces []string
+	for _, user := range included.Users {
+		users = append(users, user.Username)
+	}
+	for _, space := range included.Spaces {
+		spaces = append(spaces, space.Name)
+	}
+	if fmt.Sprint(users) != "[alice bob]" || fmt.Sprint(spaces) != "[space-1 space-2]" ||
+		len(included.Organizations) != 0 {
+		t.Errorf("included users = %v, spaces = %v, organizations = %v, want each resource once",
+			users, spaces, included.Organizations)
+	}
+
+	want := "[space_developer:alice@space-1/ space_manager:alice@space-2/ " +
+		"space_developer:bob@space-1/ organization_user:bob@/]"
+	if got := describeRoles(included.EnrichRoles(roles)); fmt.Sprint(got) != want {
+		t.Errorf("roles = %v, want %s", got, want)
+	}
+	space := included.EnrichSpace(*included.Space("space-guid-2"))
+	if space.Name != "space-2" || space.Organization != nil {
+		t.Errorf("space = %+v, want space-2 without the organization, which was not included", space)
+	}
+}
+
+func TestListEnrichedJoinsIncludedResources(t *testing.T) {
+	server := newRoleServer(t)
+	client := newClient(t, server)
+	pagination := cf.PaginationOptions{Page: 1, PerPage: 1}
+
+	roles, err := client.ListRoleEnriched(cf.ListRoleOptions{PaginationOptions: pagination})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[space_developer:alice@space-1/org space_manager:alice@space-2/org " +
+		"space_developer:bob@space-1/org organization_user:bob@/org]"
+	if got := describeRoles(roles); fmt.Sprint(got) != want {
+		t.Errorf("roles = %v, want %s", got, want)
+	}
+
+	spaces, err := client.ListSpacesEnriched(cf.ListSpacesOptions{PaginationOptions: pagination})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, space := range spaces {
+		if space.OrganizationName() != "org" {
+			t.Errorf("space %s has organization %q, want org", space.Name, space.OrganizationName())
+		}
+	}
+	if len(spaces) != 2 {
+		t.Errorf("spaces = %v, want 2 spaces", spaces)
+	}
+}
@@ -0,0 +1,186 @@
+package cf_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// newRootServer starts a Cloud Controller which only serves a root document with the given links
+func newRootServer(t *testing.T, login, uaa string) *httptest.Server {
+	t.Helper()
+	links := map[string]any{"self": map[string]string{"href": "https://api.example.com"}}
+	if login != "" {
+		links["login"] = map[string]string{"href": login}
+	}
+	if uaa != "" {
+		links["uaa"] = map[string]string{"href": uaa}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"links": links})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		uaa      string
+		config   cf.CloudFoundryConfig
+		wantAuth string
+		wantUAA  string
+	}{
+		{
+			name:     "login and UAA",
+			login:    "https://login.example.com",
+			uaa:      "https://uaa.example.com",
+			wantAuth: "https://login.example.com",
+			wantUAA:  "https://uaa.example.com",
+		},
+		{
+			name:     "UAA only",
+			uaa:      "https://uaa.example.com",
+			wantAuth: "https://uaa.example.com",
+			wantUAA:  "https://uaa.example.com",
+		},
+		{
+			name:     "configured endpoints are kept",
+			login:    "https://login.example.com",
+			uaa:      "https://uaa.example.com",
+			config:   cf.CloudFoundryConfig{AuthEndpoint: "https://auth.internal", UAAEndpoint: "https://uaa.internal"},
+			wantAuth: "https://auth.internal",
+			wantUAA:  "https://uaa.internal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRootServer(t, tt.login, tt.uaa)
+			config := tt.config
+			config.APIEndpoint = server.URL
+			root, err := config.Discover()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root.Links.Self == nil || root.Links.Self.Href != "https://api.example.com" {
+				t.Errorf("root = %+v, want the root document", root)
+			}
+			if config.AuthEndpoint != tt.wantAuth || config.UAAEndpoint != tt.wantUAA {
+				t.Errorf("auth endpoint = %q, UAA endpoint = %q, want %q and %q",
+					config.AuthEndpoint, config.UAAEndpoint, tt.wantAuth, tt.wantUAA)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromAPI(t *testing.T) {
+	server := newServer(t, 1)
+	config, err := cf.NewConfigFromAPI(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.APIEndpoint != server.URL || config.AuthEndpoint != server.URL || config.UAAEndpoint != server.URL ||
+		config.OAuthClientID != "cf" {
+		t.Errorf("config = %+v, want the endpoints of the server and the cf client", config)
+	}
+
+	// the discovered config only needs credentials to create a client
+	config.Username, config.Password = cftest.DefaultUsername, cftest.DefaultPassword
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := cf.NewConfigFromAPI(""); !errors.Is(err, cf.InvalidConfigErr) {
+		t.Errorf("err = %v, want InvalidConfigErr for a missing API endpoint", err)
+	}
+	if _, err := cf.NewConfigFromAPI(server.URL + "/missing"); err == nil {
+		t.Error("discovery without root document succeeded")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := cf.CloudFoundryConfig{
+		APIEndpoint:  "https://api.example.com",
+		AuthEndpoint: "https://login.example.com",
+		Username:     "admin",
+		Password:     "secret",
+	}
+	tests := []struct {
+		name    string
+		modify  func(config *cf.CloudFoundryConfig)
+		wantErr []error
+	}{
+		{"valid", func(*cf.CloudFoundryConfig) {}, nil},
+		{"missing API endpoint", func(c *cf.CloudFoundryConfig) { c.APIEndpoint = "" }, []error{cf.InvalidConfigErr}},
+		{"relative API endpoint", func(c *cf.CloudFoundryConfig) { c.APIEndpoint = "api.example.com" },
+			[]error{cf.InvalidConfigErr}},
+		{"missing auth endpoint", func(c *cf.CloudFoundryConfig) { c.AuthEndpoint = "" }, []error{cf.InvalidConfigErr}},
+		{"missing password", func(c *cf.CloudFoundryConfig) { c.Password = "" }, []error{cf.InvalidConfigErr}},
+		{"missing passcode", func(c *cf.CloudFoundryConfig) { c.GrantType = cf.PasscodeGrant }, []error{cf.InvalidConfigErr}},
+		{"client credentials", func(c *cf.CloudFoundryConfig) {
+			c.GrantType, c.Username, c.Password, c.OAuthClientID = cf.ClientCredentialsGrant, "", "", "client"
+		}, nil},
+		{"unsupported grant", func(c *cf.CloudFoundryConfig) { c.GrantType = "implicit" },
+			[]error{cf.InvalidConfigErr, cf.UnsupportedGrantErr}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			err := config.Validate()
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("err = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRootAndInfoAreCached(t *testing.T) {
+	var rootRequests, infoRequests atomic.Int32
+	server := newServer(t, 1)
+	client := newClient(t, server,
+		cf.WithTransportWrapper(countRequests("/", &rootRequests)),
+		cf.WithTransportWrapper(countRequests("/v3/info", &infoRequests)))
+
+	for range 3 {
+		root, err := client.GetRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root.CloudControllerVersion() == "" {
+			t.Errorf("root = %+v, want the version of the Cloud Controller", root)
+		}
+		info, err := client.GetInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name != "cftest" {
+			t.Errorf("info = %+v, want the info of the server", info)
+		}
+	}
+	if rootRequests.Load() != 1 || infoRequests.Load() != 1 {
+		t.Errorf("root was fetched %d times and info %d times, want both to be fetched once",
+			rootRequests.Load(), infoRequests.Load())
+	}
+}
@@ -0,0 +1,119 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+// fastPolls is a poll policy which doesn't slow down the tests
+var fastPolls = cf.WithJobPollPolicy(cf.JobPollPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond})
+
+// countJobPolls returns a transport wrapper which counts the requests for jobs
+func countJobPolls(count *atomic.Int32) func(next http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(r.URL.Path, "/v3/jobs/") {
+				count.Add(1)
+			}
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// deleteSpace starts the deletion of the first space of the server
+func deleteSpace(t *testing.T, server *cftest.Server, client *cf.CloudFoundryClient) *cf.JobHandle {
+	t.Helper()
+	handle, err := client.DeleteAsync("/v3/spaces/" + server.Spaces()[0].Guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handle
+}
+
+func TestWaitForJobPollsUntilComplete(t *testing.T) {
+	var polls atomic.Int32
+	server := newServer(t, 1, cftest.WithJobPolls(3))
+	client := newClient(t, server, fastPolls, cf.WithTransportWrapper(countJobPolls(&polls)))
+
+	handle := deleteSpace(t, server, client)
+	if handle.GUID == "" || !strings.HasSuffix(handle.URL, "/v3/jobs/"+handle.GUID) {
+		t.Errorf("handle = %+v, want the job of the Location header", handle)
+	}
+	job, err := handle.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != models.JobStateComplete || job.Guid != handle.GUID {
+		t.Errorf("job = %+v, want the completed job %s", job, handle.GUID)
+	}
+	if got := polls.Load(); got != 4 {
+		t.Errorf("job was polled %d times, want 3 times while processing and once when complete", got)
+	}
+	if spaces := server.Spaces(); len(spaces) != 0 {
+		t.Errorf("spaces = %v, want the space to be deleted", names(spaces))
+	}
+}
+
+func TestWaitForJobReturnsFailedJob(t *testing.T) {
+	server := newServer(t, 1, cftest.WithJobPolls(1))
+	client := newClient(t, server, fastPolls)
+	server.FailNextJob(models.JobError{Code: 10008, Title: "CF-UnprocessableEntity", Detail: "space is not empty"})
+
+	job, err := client.WaitForJobWithContext(context.Background(), deleteSpace(t, server, client).GUID)
+	var jobErr *cf.JobFailedError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("err = %v, want a JobFailedError", err)
+	}
+	if job == nil || job.State != models.JobStateFailed || jobErr.Job != job {
+		t.Errorf("job = %+v, want the failed job", job)
+	}
+	var cfErr cf.CloudFoundryError
+	if !errors.As(err, &cfErr) || cfErr.Code != 10008 || cfErr.Detail != "space is not empty" {
+		t.Errorf("err = %v, want the error of the job", err)
+	}
+	if spaces := server.Spaces(); len(spaces) != 1 {
+		t.Errorf("spaces = %v, want the space to be kept", names(spaces))
+	}
+}
+
+func TestDeleteAsyncRequiresJobLocation(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server)
+	server.InjectFault(cftest.Fault{Method: http.MethodDelete, Status: http.StatusAccepted})
+
+	handle, err := client.DeleteAsyncWithContext(context.Background(), "/v3/spaces/"+server.Spaces()[0].Guid)
+	if !errors.Is(err, cf.MissingJobLocationErr) {
+		t.Errorf("handle = %+v, err = %v, want MissingJobLocationErr", handle, err)
+	}
+}
+
+func TestWaitForJobStopsWhenContextIsCancelled(t *testing.T) {
+	var polls atomic.Int32
+	server := newServer(t, 1, cftest.WithJobPolls(10))
+	client := newClient(t, server,
+		cf.WithJobPollPolicy(cf.JobPollPolicy{InitialInterval: time.Hour}),
+		cf.WithTransportWrapper(countJobPolls(&polls)))
+	handle := deleteSpace(t, server, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := handle.WaitWithContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waiting took %v, want it to stop at the deadline", elapsed)
+	}
+	if got := polls.Load(); got != 1 {
+		t.Errorf("job was polled %d times, want it to be polled once before waiting", got)
+	}
+}
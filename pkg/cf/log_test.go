@@ -0,0 +1,69 @@
+package cf_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+func TestLoggingRedactsSecrets(t *testing.T) {
+	for _, verbosity := range []cf.LogVerbosity{cf.LogRequests, cf.LogHeaders, cf.LogBodies} {
+		t.Run(map[cf.LogVerbosity]string{
+			cf.LogRequests: "requests",
+			cf.LogHeaders:  "headers",
+			cf.LogBodies:   "bodies",
+		}[verbosity], func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			server := newServer(t, 1,
+				cftest.WithUser("alice", "user-password"),
+				cftest.WithOAuthClient("cf", "client-secret"))
+			client := newClient(t, server, cf.WithLogger(logger), cf.WithLogVerbosity(verbosity))
+
+			secrets := []string{
+				"user-password",
+				"client-secret",
+				base64.StdEncoding.EncodeToString([]byte("cf:client-secret")),
+			}
+			addTokens := func() {
+				token := client.GetTokenInfo()
+				secrets = append(secrets, token.AccessToken, token.RefreshToken)
+			}
+			addTokens()
+			// the refresh token is sent to the UAA and new tokens are returned
+			server.ExpireTokens()
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			addTokens()
+			// secret query parameters of API requests are redacted as well
+			secrets = append(secrets, "query-secret")
+			_, _ = client.SendRequest("GET", "/v3/spaces",
+				cf.WithQueryParams(map[string]string{"access_token": "query-secret"}))
+
+			log := buf.String()
+			want := []string{"uaa token request", "cf api request", "REDACTED"}
+			if verbosity >= cf.LogHeaders {
+				want = append(want, "request_headers")
+			}
+			if verbosity >= cf.LogBodies {
+				want = append(want, "response_body")
+			}
+			for _, msg := range want {
+				if !strings.Contains(log, msg) {
+					t.Errorf("log doesn't contain %q:\n%s", msg, log)
+				}
+			}
+			for _, secret := range secrets {
+				if strings.Contains(log, secret) {
+					t.Errorf("log contains the secret %q:\n%s", secret, log)
+				}
+			}
+		})
+	}
+}
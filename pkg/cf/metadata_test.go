@@ -0,0 +1,54 @@
+package cf_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+)
+
+func TestCreateSpaceSendsMetadata(t *testing.T) {
+	var body map[string]json.RawMessage
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Method == http.MethodPost && r.URL.Path == "/v3/spaces" {
+				data, err := io.ReadAll(r.Body)
+				if err != nil {
+					return nil, err
+				}
+				if err := json.Unmarshal(data, &body); err != nil {
+					return nil, err
+				}
+				r.Body = io.NopCloser(bytes.NewReader(data))
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	server := newServer(t, 0)
+	client := newClient(t, server, cf.WithTransportWrapper(record))
+
+	space, err := client.CreateSpace("dev", orgGUID, cf.CreateSpaceOptions{
+		Labels:      map[string]string{"env": "dev"},
+		Annotations: map[string]string{"contact": "team@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := body["labels"]; ok {
+		t.Errorf("body = %v, want the labels to be nested under metadata", body)
+	}
+	want := `{"annotations":{"contact":"team@example.com"},"labels":{"env":"dev"}}`
+	if got := string(body["metadata"]); got != want {
+		t.Errorf("metadata = %s, want %s", got, want)
+	}
+	for _, got := range []any{space.Metadata, server.Spaces()[0].Metadata} {
+		if fmt.Sprint(got) != "{map[env:dev] map[contact:team@example.com]}" {
+			t.Errorf("metadata = %v, want the labels and annotations of the request", got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/go-resty/resty/v2"
+)
+
+// requestLog records the requests passing through middleware
+type requestLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+// middleware returns a middleware which records the given name before and after the request is handled
+func (l *requestLog) middleware(name string) cf.Middleware {
+	return func(next cf.RequestHandler) cf.RequestHandler {
+		return func(ctx context.Context, r *cf.MiddlewareRequest) cf.MiddlewareResult {
+			l.add(name + ">")
+			result := next(ctx, r)
+			l.add("<" + name)
+			return result
+		}
+	}
+}
+
+// requests returns a middleware which records the kind and path of all requests
+func (l *requestLog) requests() cf.Middleware {
+	return func(next cf.RequestHandler) cf.RequestHandler {
+		return func(ctx context.Context, r *cf.MiddlewareRequest) cf.MiddlewareResult {
+			u, err := url.Parse(r.URL)
+			if err != nil {
+				return cf.MiddlewareResult{Err: err}
+			}
+			l.add(fmt.Sprintf("%s %s", r.Kind, u.Path))
+			return next(ctx, r)
+		}
+	}
+}
+
+// add records the entry
+func (l *requestLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// String returns the recorded entries and resets the log
+func (l *requestLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := fmt.Sprint(l.entries)
+	l.entries = nil
+	return s
+}
+
+func TestMiddlewareIsCalledInOrder(t *testing.T) {
+	var log requestLog
+	server := newServer(t, 1)
+	client := newClient(t, server,
+		cf.WithMiddleware(log.middleware("first"), log.middleware("second")),
+		cf.WithMiddleware(log.middleware("third")))
+
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.String(), "[first> second> third> <third <second <first]"; got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestMiddlewareCanRewriteRequests(t *testing.T) {
+	var header atomic.Value
+	server := newServer(t, 1)
+	config := server.Config()
+	// the Cloud Controller is only reachable through the middleware
+	config.APIEndpoint = "http://cloud-controller.invalid"
+	rewrite := func(next cf.RequestHandler) cf.RequestHandler {
+		return func(ctx context.Context, r *cf.MiddlewareRequest) cf.MiddlewareResult {
+			r.URL = strings.Replace(r.URL, config.APIEndpoint, server.URL, 1)
+			r.Modifiers = append(r.Modifiers, func(r *resty.Request) {
+				r.SetHeader("X-Correlation-ID", "correlation-id")
+			})
+			return next(ctx, r)
+		}
+	}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/v3/spaces" {
+				header.Store(r.Header.Get("X-Correlation-ID"))
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	client, err := config.NewClient(cf.WithMiddleware(rewrite), cf.WithTransportWrapper(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spaces, err := client.ListSpaces(cf.ListSpacesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(spaces); fmt.Sprint(got) != "[space-1]" {
+		t.Errorf("spaces = %v, want [space-1]", got)
+	}
+	if got := header.Load(); got != "correlation-id" {
+		t.Errorf("X-Correlation-ID = %v, want the header of the middleware", got)
+	}
+}
+
+func TestMiddlewareCanShortCircuitRequests(t *testing.T) {
+	var requests atomic.Int32
+	maintenance := errors.New("maintenance window")
+	server := newServer(t, 1)
+	client := newClient(t, server,
+		cf.WithTransportWrapper(countRequests("/v3/spaces", &requests)),
+		cf.WithMiddleware(func(cf.RequestHandler) cf.RequestHandler {
+			return func(context.Context, *cf.MiddlewareRequest) cf.MiddlewareResult {
+				return cf.MiddlewareResult{Err: maintenance}
+			}
+		}))
+
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.Is(err, maintenance) {
+		t.Errorf("err = %v, want the error of the middleware", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("%d requests were sent, want none", got)
+	}
+}
+
+func TestAuthMiddlewareWrapsTokenRequests(t *testing.T) {
+	var apiLog, authLog requestLog
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithMiddleware(apiLog.requests()), cf.WithAuthMiddleware(authLog.requests()))
+	if got, want := authLog.String(), "[token /oauth/token]"; got != want {
+		t.Errorf("auth middleware requests = %s, want %s", got, want)
+	}
+
+	server.ExpireTokens()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetRoot(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := apiLog.String(), "[api /v3/spaces]"; got != want {
+		t.Errorf("middleware requests = %s, want %s", got, want)
+	}
+	if got, want := authLog.String(), "[token /oauth/token unauthenticated /]"; got != want {
+		t.Errorf("auth middleware requests = %s, want %s", got, want)
+	}
+}
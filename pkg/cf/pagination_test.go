@@ -0,0 +1,226 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+// recordQueries returns a middleware which records the raw queries of all requests
+func recordQueries(queries *[]string) cf.Middleware {
+	var mu sync.Mutex
+	return func(next cf.RequestHandler) cf.RequestHandler {
+		return func(ctx context.Context, r *cf.MiddlewareRequest) cf.MiddlewareResult {
+			result := next(ctx, r)
+			if result.Response != nil {
+				mu.Lock()
+				*queries = append(*queries, result.Response.Request.RawRequest.URL.RawQuery)
+				mu.Unlock()
+			}
+			return result
+		}
+	}
+}
+
+// assertSingleValues fails if a query parameter is sent more than once
+func assertSingleValues(t *testing.T, queries []string) {
+	t.Helper()
+	for _, raw := range queries {
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, values := range query {
+			if len(values) > 1 {
+				t.Errorf("query %q sends %s %d times", raw, key, len(values))
+			}
+		}
+	}
+}
+
+func TestListWalksAllPagesWithPagination(t *testing.T) {
+	server := newServer(t, 7)
+
+	tests := []struct {
+		name    string
+		options cf.PaginationOptions
+		want    string
+		pages   int
+	}{
+		{"from first page", cf.PaginationOptions{Page: 1, PerPage: 2}, "[space-1 space-2 space-3 space-4 space-5 space-6 space-7]", 4},
+		{"from second page", cf.PaginationOptions{Page: 2, PerPage: 2}, "[space-3 space-4 space-5 space-6 space-7]", 3},
+		{"single item pages", cf.PaginationOptions{Page: 5, PerPage: 1}, "[space-5 space-6 space-7]", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			client := newClient(t, server, cf.WithMiddleware(recordQueries(&queries)))
+
+			spaces, err := client.ListSpaces(cf.ListSpacesOptions{PaginationOptions: tt.options})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(names(spaces)); got != tt.want {
+				t.Errorf("spaces = %s, want %s", got, tt.want)
+			}
+			if len(queries) != tt.pages {
+				t.Errorf("fetched %d pages, want %d: %v", len(queries), tt.pages, queries)
+			}
+			assertSingleValues(t, queries)
+		})
+	}
+}
+
+func TestIteratorWalksAllPagesWithPagination(t *testing.T) {
+	server := newServer(t, 6)
+	client := newClient(t, server)
+
+	it := client.ListSpacesIterator(cf.ListSpacesOptions{
+		PaginationOptions: cf.PaginationOptions{Page: 1, PerPage: 2},
+		Names:             []string{"space-1", "space-2", "space-3", "space-4", "space-5", "space-6"},
+	})
+	var got []string
+	for it.HasNext() {
+		page, err := it.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprint(names(page)))
+	}
+	if want := "[[space-1 space-2] [space-3 space-4] [space-5 space-6]]"; fmt.Sprint(got) != want {
+		t.Errorf("pages = %v, want %s", got, want)
+	}
+}
+
+func TestCollectConcurrentlyWalksAllPagesWithPagination(t *testing.T) {
+	server := newServer(t, 9)
+	var queries []string
+	client := newClient(t, server, cf.WithMiddleware(recordQueries(&queries)))
+
+	query := cf.NewListQuery().Pagination(cf.PaginationOptions{Page: 1, PerPage: 2}).OrderBy(cf.OrderByNameAsc)
+	spaces, err := cf.FetchAllPagesConcurrently[models.Space](
+		context.Background(), client, "GET", "/v3/spaces", 3, cf.WithListQuery(query),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[space-1 space-2 space-3 space-4 space-5 space-6 space-7 space-8 space-9]"
+	if got := fmt.Sprint(names(spaces)); got != want {
+		t.Errorf("spaces = %s, want %s", got, want)
+	}
+	if len(queries) != 5 {
+		t.Errorf("fetched %d pages, want 5: %v", len(queries), queries)
+	}
+	assertSingleValues(t, queries)
+}
+
+// perPage lists the spaces with the given number of spaces per page
+func perPage(n int) cf.ListSpacesOptions {
+	return cf.ListSpacesOptions{PaginationOptions: cf.PaginationOptions{Page: 1, PerPage: n}}
+}
+
+func TestCollectConcurrentlyLimitsWorkers(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	track := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for current := maxInFlight.Load(); n > current && !maxInFlight.CompareAndSwap(current, n); {
+				current = maxInFlight.Load()
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	server := newServer(t, 20)
+	client := newClient(t, server, cf.WithTransportWrapper(track))
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: 10 * time.Millisecond})
+
+	spaces, err := client.ListSpacesIterator(perPage(1)).CollectConcurrently(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spaces) != 20 || spaces[0].Name != "space-1" || spaces[19].Name != "space-20" {
+		t.Errorf("spaces = %v, want all 20 spaces in order", names(spaces))
+	}
+	if got := maxInFlight.Load(); got != 3 {
+		t.Errorf("%d pages were fetched concurrently, want 3", got)
+	}
+}
+
+func TestCollectConcurrentlyCancelsPagesAfterError(t *testing.T) {
+	var requests, cancelled atomic.Int32
+	failSecondPage := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != "/v3/spaces" {
+				return next.RoundTrip(r)
+			}
+			requests.Add(1)
+			if r.URL.Query().Get("page") == "2" {
+				// fail once the pages of the other workers are in flight
+				for deadline := time.Now().Add(time.Second); requests.Load() < 5 && time.Now().Before(deadline); {
+					time.Sleep(time.Millisecond)
+				}
+				body := `{"errors":[{"code":10010,"title":"CF-ResourceNotFound","detail":"Page not found"}]}`
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Request:    r,
+				}, nil
+			}
+			resp, err := next.RoundTrip(r)
+			if errors.Is(err, context.Canceled) {
+				cancelled.Add(1)
+			}
+			return resp, err
+		})
+	}
+	server := newServer(t, 20)
+	client := newClient(t, server, cf.WithTransportWrapper(failSecondPage))
+	// every page except the first one takes longer than the test
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Times: 1})
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: 10 * time.Second})
+
+	start := time.Now()
+	_, err := client.ListSpacesIterator(perPage(1)).CollectConcurrently(context.Background(), 4)
+	if !errors.Is(err, cf.NotFoundErr) {
+		t.Errorf("err = %v, want the error of the second page", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("collecting took %v, want the pages to be cancelled", elapsed)
+	}
+	// the first page, the failing second page and the pages of the other three workers
+	if got := requests.Load(); got != 5 {
+		t.Errorf("%d pages were requested, want no pages to be requested after the error", got)
+	}
+	if got := cancelled.Load(); got == 0 {
+		t.Error("no page request was cancelled")
+	}
+}
+
+func TestCollectConcurrentlyRespectsMaxPages(t *testing.T) {
+	var requests atomic.Int32
+	server := newServer(t, 5)
+	client := newClient(t, server, cf.WithTransportWrapper(countRequests("/v3/spaces", &requests)))
+
+	it := client.ListSpacesIterator(perPage(1))
+	it.MaxPages = 3
+	if _, err := it.CollectConcurrently(context.Background(), 2); !errors.Is(err, cf.MaxPaginationPagesExceededErr) {
+		t.Errorf("err = %v, want MaxPaginationPagesExceededErr", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d pages were requested, want only the first page which reports the total pages", got)
+	}
+}
@@ -0,0 +1,268 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/labels"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+func TestListQueryZeroValue(t *testing.T) {
+	var q cf.ListQuery
+	if params := q.Params(); len(params) != 0 {
+		t.Errorf("params = %v, want none", params)
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	q.Names("a", "b").Page(2).PerPage(10).
+		CreatedAts(cf.TimestampFilter{Operator: cf.TimestampGreaterThan, Time: created})
+	want := map[string]string{
+		"names":           "a,b",
+		"page":            "2",
+		"per_page":        "10",
+		"created_ats[gt]": "2024-01-02T03:04:05Z",
+	}
+	if got := q.Params(); !reflect.DeepEqual(got, want) {
+		t.Errorf("params = %v, want %v", got, want)
+	}
+
+	for _, query := range []*cf.ListQuery{{}, new(cf.ListQuery)} {
+		if got := query.PerPage(0).Params(); got["per_page"] != strconv.Itoa(cf.MaxItemsPerPage) {
+			t.Errorf("params = %v, want the maximum per_page", got)
+		}
+	}
+}
+
+func TestListQueryLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *cf.ListQuery
+		want  string
+	}{
+		{"selector", cf.NewListQuery().Labels(labels.New().Eq("env", "prod").Exists("owner")), "env=prod,owner"},
+		{"combined", cf.NewListQuery().LabelSelector("env=prod").Labels(labels.MustParse("!deprecated")), "env=prod,!deprecated"},
+		{"nil selector", cf.NewListQuery().LabelSelector("env=prod").Labels(nil), "env=prod"},
+		{"empty selector", cf.NewListQuery().Labels(labels.New()), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.query.Params()["label_selector"]; got != tt.want {
+				t.Errorf("label_selector = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	query := cf.NewListQuery().Labels(labels.New().Eq("-invalid", "x"))
+	if err := query.Err(); !errors.Is(err, labels.InvalidSelectorErr) {
+		t.Errorf("Err() = %v, want InvalidSelectorErr", err)
+	}
+	if _, ok := query.Params()["label_selector"]; ok {
+		t.Errorf("params = %v, want no label selector", query.Params())
+	}
+}
+
+func TestListOptionsSendLabelSelector(t *testing.T) {
+	var queries []string
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithMiddleware(recordQueries(&queries)))
+	spaces, err := client.ListSpaces(cf.ListSpacesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaceGUID := spaces[0].Guid
+
+	selector := labels.New().Eq("env", "prod")
+	invalid := labels.New().Eq("env", "-prod")
+	tests := []struct {
+		name string
+		list func(selector *labels.Selector) error
+		iter func(selector *labels.Selector) error
+	}{
+		{
+			name: "spaces",
+			list: func(selector *labels.Selector) error {
+				_, err := client.ListSpaces(cf.ListSpacesOptions{LabelSelector: "owner", Labels: selector})
+				return err
+			},
+			iter: func(selector *labels.Selector) error {
+				_, err := client.ListSpacesIterator(cf.ListSpacesOptions{LabelSelector: "owner", Labels: selector}).
+					NextPage(context.Background())
+				return err
+			},
+		},
+		{
+			name: "users",
+			list: func(selector *labels.Selector) error {
+				_, err := client.ListUsers(cf.ListUsersOptions{LabelSelector: "owner", Labels: selector})
+				return err
+			},
+			iter: func(selector *labels.Selector) error {
+				_, err := client.ListUsersIterator(cf.ListUsersOptions{LabelSelector: "owner", Labels: selector}).
+					NextPage(context.Background())
+				return err
+			},
+		},
+		{
+			name: "users for space",
+			list: func(selector *labels.Selector) error {
+				_, err := client.ListUsersForSpace(spaceGUID,
+					cf.ListUsersForSpaceOptions{LabelSelector: "owner", Labels: selector})
+				return err
+			},
+			iter: func(selector *labels.Selector) error {
+				_, err := client.ListUsersForSpaceIterator(spaceGUID,
+					cf.ListUsersForSpaceOptions{LabelSelector: "owner", Labels: selector}).NextPage(context.Background())
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, send := range []func(selector *labels.Selector) error{tt.list, tt.iter} {
+				queries = nil
+				if err := send(selector); err != nil {
+					t.Fatal(err)
+				}
+				if len(queries) != 1 {
+					t.Fatalf("sent %d requests, want 1", len(queries))
+				}
+				query, _ := url.ParseQuery(queries[0])
+				if got := query.Get("label_selector"); got != "owner,env=prod" {
+					t.Errorf("label_selector = %q, want owner,env=prod", got)
+				}
+
+				// invalid selectors are reported before a request is sent
+				queries = nil
+				if err := send(invalid); !errors.Is(err, labels.InvalidSelectorErr) || len(queries) != 0 {
+					t.Errorf("err = %v after %d requests, want InvalidSelectorErr without a request", err, len(queries))
+				}
+			}
+		})
+	}
+}
+
+func TestListQueryTimestamps(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		filters []cf.TimestampFilter
+		want    map[string]string
+	}{
+		{
+			name: "range",
+			filters: []cf.TimestampFilter{
+				{Operator: cf.TimestampGreaterThan, Time: day(1)},
+				{Operator: cf.TimestampLessThanOrEqual, Time: day(5)},
+			},
+			want: map[string]string{
+				"created_ats[gt]":  "2024-01-01T00:00:00Z",
+				"created_ats[lte]": "2024-01-05T00:00:00Z",
+			},
+		},
+		{
+			name: "repeated lower bound",
+			filters: []cf.TimestampFilter{
+				{Operator: cf.TimestampGreaterThanOrEqual, Time: day(3)},
+				{Operator: cf.TimestampGreaterThanOrEqual, Time: day(2)},
+				{Operator: cf.TimestampGreaterThan, Time: day(1)},
+				{Operator: cf.TimestampGreaterThan, Time: day(4)},
+			},
+			want: map[string]string{
+				"created_ats[gte]": "2024-01-03T00:00:00Z",
+				"created_ats[gt]":  "2024-01-04T00:00:00Z",
+			},
+		},
+		{
+			name: "repeated upper bound",
+			filters: []cf.TimestampFilter{
+				{Operator: cf.TimestampLessThan, Time: day(3)},
+				{Operator: cf.TimestampLessThan, Time: day(2)},
+				{Operator: cf.TimestampLessThanOrEqual, Time: day(4)},
+				{Operator: cf.TimestampLessThanOrEqual, Time: day(5)},
+			},
+			want: map[string]string{
+				"created_ats[lt]":  "2024-01-02T00:00:00Z",
+				"created_ats[lte]": "2024-01-04T00:00:00Z",
+			},
+		},
+		{
+			name: "equal",
+			filters: []cf.TimestampFilter{
+				{Time: day(1)},
+				{Time: day(2).In(time.FixedZone("CET", 3600))},
+			},
+			want: map[string]string{"created_ats": "2024-01-01T00:00:00Z,2024-01-02T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cf.NewListQuery().CreatedAts(tt.filters...).Params(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("params = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSpacesByTimeRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	fixtures := cftest.Fixtures{
+		Organizations: []models.Organization{{Guid: orgGUID, Name: "org"}},
+	}
+	for i := 1; i <= 5; i++ {
+		space := models.Space{Name: fmt.Sprintf("space-%d", i), CreatedAt: day(i)}
+		space.Relationships.Organization.Data.Guid = orgGUID
+		fixtures.Spaces = append(fixtures.Spaces, space)
+	}
+	server := cftest.NewServer(cftest.WithFixtures(fixtures))
+	t.Cleanup(server.Close)
+	client := newClient(t, server)
+
+	tests := []struct {
+		name    string
+		filters []cf.TimestampFilter
+		want    string
+	}{
+		{
+			name: "range",
+			filters: []cf.TimestampFilter{
+				{Operator: cf.TimestampGreaterThan, Time: day(1)},
+				{Operator: cf.TimestampLessThan, Time: day(5)},
+			},
+			want: "[space-2 space-3 space-4]",
+		},
+		{
+			name: "repeated operators",
+			filters: []cf.TimestampFilter{
+				{Operator: cf.TimestampGreaterThanOrEqual, Time: day(2)},
+				{Operator: cf.TimestampGreaterThanOrEqual, Time: day(3)},
+				{Operator: cf.TimestampLessThanOrEqual, Time: day(5)},
+				{Operator: cf.TimestampLessThanOrEqual, Time: day(4)},
+			},
+			want: "[space-3 space-4]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spaces, err := client.ListSpaces(cf.ListSpacesOptions{
+				TimestampFilterOptions: cf.TimestampFilterOptions{CreatedAts: tt.filters},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(names(spaces)); got != tt.want {
+				t.Errorf("spaces = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// waitFor polls the condition until it is true or fails the test after a second
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRateLimitSpacesRequests(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRateLimit(cf.RateLimit{RequestsPerSecond: 20, Burst: 2}))
+
+	// the burst is sent at once, every further request waits for the budget to be refilled
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 150ms for 3 requests over the burst", elapsed)
+	}
+	if stats := client.RateLimitStats(); stats.Burst != 2 || stats.Available >= 1 || stats.Waiting != 0 {
+		t.Errorf("stats = %+v, want an exhausted budget", stats)
+	}
+}
+
+func TestRateLimitLimitsRequestsInFlight(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for current := maxInFlight.Load(); n > current && !maxInFlight.CompareAndSwap(current, n); {
+				current = maxInFlight.Load()
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRateLimit(cf.RateLimit{MaxInFlight: 2}), cf.WithTransportWrapper(count))
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: 20 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitFor(t, func() bool {
+		stats := client.RateLimitStats()
+		return stats.InFlight == 2 && stats.Waiting == 4
+	})
+	wg.Wait()
+	if got := maxInFlight.Load(); got != 2 {
+		t.Errorf("%d requests were sent concurrently, want 2", got)
+	}
+	if stats := client.RateLimitStats(); stats.InFlight != 0 || stats.Waiting != 0 || stats.MaxInFlight != 2 {
+		t.Errorf("stats = %+v, want no requests in flight", stats)
+	}
+}
+
+func TestRateLimitDoesNotCountWaitingRequestsInFlight(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRateLimit(cf.RateLimit{RequestsPerSecond: 0.1, MaxInFlight: 1}))
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next request waits for the budget without occupying the only slot
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{})
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		return client.RateLimitStats().Waiting == 1
+	})
+	if stats := client.RateLimitStats(); stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want the waiting request not to be in flight", stats)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want the cancellation of the context", err)
+	}
+	if stats := client.RateLimitStats(); stats.Waiting != 0 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want no waiting requests", stats)
+	}
+}
+
+func TestRateLimitAdaptsToHeaders(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRateLimit(cf.RateLimit{RequestsPerSecond: 100, Burst: 10, AdaptToHeaders: true}))
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	server.InjectFault(cftest.Fault{
+		Path:   "/v3/spaces",
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{
+			"X-Ratelimit-Limit":     {"100"},
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+		},
+		Times: 1,
+	})
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err == nil {
+		t.Fatal("expected the rate limited request to fail")
+	}
+	stats := client.RateLimitStats()
+	if stats.ServerLimit != 100 || stats.ServerRemaining != 0 || !stats.ServerReset.Equal(reset) ||
+		!stats.PausedUntil.Equal(reset) || stats.Available >= 1 {
+		t.Errorf("stats = %+v, want requests to be paused until %v", stats, reset)
+	}
+
+	// all requests wait until the reset
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the request to wait for the reset", err)
+	}
+}
+
+func TestRateLimitReturnsBudgetOfRequestsCancelledWhileWaitingForSlot(t *testing.T) {
+	server := newServer(t, 1)
+	// the budget is practically not refilled during the test
+	client := newClient(t, server, cf.WithRateLimit(cf.RateLimit{RequestsPerSecond: 0.001, Burst: 2, MaxInFlight: 1}))
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: 200 * time.Millisecond, Times: 1})
+
+	// the first request occupies the only slot
+	first := make(chan error)
+	go func() {
+		_, err := client.ListSpaces(cf.ListSpacesOptions{})
+		first <- err
+	}()
+	waitFor(t, func() bool {
+		return client.RateLimitStats().InFlight == 1
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	second := make(chan error)
+	go func() {
+		_, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{})
+		second <- err
+	}()
+	waitFor(t, func() bool {
+		return client.RateLimitStats().Waiting == 1
+	})
+	cancel()
+	if err := <-second; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want the cancellation of the context", err)
+	}
+	if stats := client.RateLimitStats(); stats.Available < 1 || stats.InFlight != 1 || stats.Waiting != 0 {
+		t.Errorf("stats = %+v, want the budget of the cancelled request to be returned", stats)
+	}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,62 +1,257 @@
 package cf
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2"
+	"net/http"
 	"time"
 )
 
 // makeAuthenticationRequest is a helper function to make an authentication request.
 // it fills some default parameters for authentication requests.
-func (cfg *CloudFoundryConfig) makeAuthenticationRequest(authParams map[string]string) (*resty.Response, error) {
-	return resty.New().R().
-		EnableTrace().
-		SetQueryParams(authParams).
-		SetBasicAuth(cfg.OAuthClientID, cfg.OAuthClientSecret).
-		SetResult(new(AuthTokenInfo)).
-		Post(fmt.Sprintf("%s/oauth/token", cfg.AuthEndpoint))
+// If the UAA rejects the request, a *TokenError is returned together with the response.
+// If the request is sent on behalf of a client (see contextWithClient), it passes through the auth middleware
+// of the client and is logged by the client
+func (cfg *CloudFoundryConfig) makeAuthenticationRequest(
+	ctx context.Context,
+	authParams map[string]string,
+) (*resty.Response, error) {
+	client := clientFromContext(ctx)
+	r := &MiddlewareRequest{
+		Kind:   TokenRequest,
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf("%s/oauth/token", cfg.AuthEndpoint),
+	}
+	result := client.handleAuthRequest(ctx, r, func(ctx context.Context, r *MiddlewareRequest) MiddlewareResult {
+		httpClient, err := cfg.newRequestHTTPClient(ctx)
+		if err != nil {
+			return MiddlewareResult{Err: err}
+		}
+		request := httpClient.R().
+			SetContext(ctx).
+			EnableTrace().
+			SetQueryParams(authParams).
+			SetBasicAuth(cfg.OAuthClientID, cfg.OAuthClientSecret).
+			SetResult(new(AuthTokenInfo))
+		applyRequestModifiers(request, r.Modifiers...)
+		if client != nil {
+			client.telemetry.inject(ctx, request)
+		}
+		start := time.Now()
+		resp, err := request.Execute(r.Method, r.URL)
+		duration := time.Since(start)
+		client.logRequest(ctx, "uaa token request", request, resp, err, duration)
+		if err == nil && resp.StatusCode() >= 400 {
+			err = parseTokenErrorResponse(resp)
+		}
+		return MiddlewareResult{Response: resp, Err: err, Attempts: 1, Duration: duration}
+	})
+	return result.Response, result.Err
+}
+
+// requestToken requests a new token from the UAA using the given grant parameters.
+// If the UAA rejects the request or returns no token, a *TokenError is returned
+func (cfg *CloudFoundryConfig) requestToken(ctx context.Context, authParams map[string]string) (*AuthTokenInfo, error) {
+	resp, err := cfg.makeAuthenticationRequest(ctx, authParams)
+	if err != nil {
+		return nil, err
+	}
+	token := resp.Result().(*AuthTokenInfo)
+	if token.AccessToken == "" {
+		return nil, &TokenError{
+			StatusCode:  resp.StatusCode(),
+			ErrorCode:   "invalid_response",
+			Description: "the token response contains no access token",
+		}
+	}
+	return token, nil
 }
 
 // NewClient returns a new request httpClient which manages the authToken and refreshes it if necessary
-func (cfg *CloudFoundryConfig) NewClient() (*CloudFoundryClient, error) {
-	resp, err := cfg.makeAuthenticationRequest(map[string]string{
-		"grant_type": "password",
-		"scope":      "",
-		"username":   cfg.Username,
-		"password":   cfg.Password,
-	})
+// By default, the client authenticates using the GrantType of the config (see CloudFoundryConfig.TokenSource),
+// use WithTokenSource to authenticate using a different TokenSource.
+// If the AuthEndpoint of the config is empty, it is discovered from the Cloud Controller root document,
+// so only the APIEndpoint and the credentials are required. The config is validated before authenticating.
+// The given options are applied to the client before it authenticates
+func (cfg *CloudFoundryConfig) NewClient(options ...ClientOption) (*CloudFoundryClient, error) {
+	return cfg.NewClientWithContext(context.Background(), options...)
+}
+
+// NewClientWithContext is like NewClient but uses the given context for the initial authentication
+func (cfg *CloudFoundryConfig) NewClientWithContext(
+	ctx context.Context,
+	options ...ClientOption,
+) (*CloudFoundryClient, error) {
+	client := &CloudFoundryClient{
+		config: cfg,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	httpClient, err := cfg.newHTTPClient(client.transportOptions)
 	if err != nil {
 		return nil, err
 	}
+	client.httpClient = httpClient
+	ctx = contextWithClient(ctx, client)
+	if client.tokenSource == nil {
+		if cfg.AuthEndpoint == "" {
+			root, err := cfg.DiscoverWithContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			client.root = root
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		source, err := cfg.TokenSource()
+		if err != nil {
+			return nil, err
+		}
+		client.tokenSource = source
+	} else if err := validateEndpoint("api_endpoint", cfg.APIEndpoint); err != nil {
+		return nil, err
+	}
 
-	token := resp.Result().(*AuthTokenInfo)
-	return &CloudFoundryClient{
-		authToken:    token,
-		lastAuthTime: time.Now(),
-		config:       cfg,
-		httpClient:   resty.New(),
-	}, nil
+	// fetch the initial authToken if no valid token has been passed
+	if client.TokenIsExpired() {
+		if err := client.RefreshTokenWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// tokenRefreshCall is a token refresh which is currently in flight.
+// Requests which need a new token while a refresh is running wait for it instead of starting their own refresh
+type tokenRefreshCall struct {
+	// done is closed as soon as the refresh finished
+	done chan struct{}
+	err  error
 }
 
 // TokenIsExpired returns true if the authToken is expired
 func (req *CloudFoundryClient) TokenIsExpired() bool {
-	return req.lastAuthTime.
-		Add(time.Duration(req.authToken.ExpiresIn)*time.Second - TokenExpirySafetyMargin).
+	req.tokenMu.Lock()
+	defer req.tokenMu.Unlock()
+	return req.tokenIsExpiredLocked()
+}
+
+// tokenIsExpiredLocked is like TokenIsExpired but expects tokenMu to be held by the caller
+// Tokens without an expiry never expire
+func (req *CloudFoundryClient) tokenIsExpiredLocked() bool {
+	if req.authToken == nil {
+		return true
+	}
+	if req.authToken.Expiry.IsZero() {
+		return false
+	}
+	return req.authToken.Expiry.
+		Add(-TokenExpirySafetyMargin).
 		Before(time.Now())
 }
 
-// RefreshToken tries to refresh the authToken
+// validToken returns a copy of the current authToken and refreshes it before if it is expired
+func (req *CloudFoundryClient) validToken(ctx context.Context) (oauth2.Token, error) {
+	req.tokenMu.Lock()
+	if !req.tokenIsExpiredLocked() {
+		token := *req.authToken
+		req.tokenMu.Unlock()
+		return token, nil
+	}
+	req.tokenMu.Unlock()
+
+	for {
+		if err := req.RefreshTokenWithContext(ctx); err != nil {
+			return oauth2.Token{}, err
+		}
+		req.tokenMu.Lock()
+		// the new token might have already been invalidated by another request
+		if req.authToken != nil {
+			token := *req.authToken
+			req.tokenMu.Unlock()
+			return token, nil
+		}
+		req.tokenMu.Unlock()
+	}
+}
+
+// invalidateToken discards the authToken if it still has the given access token,
+// so the next request fetches a new token from the TokenSource
+func (req *CloudFoundryClient) invalidateToken(accessToken string) {
+	req.tokenMu.Lock()
+	defer req.tokenMu.Unlock()
+	if req.authToken != nil && req.authToken.AccessToken == accessToken {
+		req.authToken = nil
+	}
+}
+
+// RefreshToken tries to refresh the authToken by requesting a new token from the TokenSource
 func (req *CloudFoundryClient) RefreshToken() error {
-	resp, err := req.config.makeAuthenticationRequest(map[string]string{
-		"grant_type":    "refresh_token",
-		"refresh_token": req.authToken.RefreshToken,
-	})
+	return req.RefreshTokenWithContext(context.Background())
+}
+
+// RefreshTokenWithContext is like RefreshToken but uses the given context for the token request.
+// If a refresh is already in flight, no additional refresh is started and the result of the running one is returned
+func (req *CloudFoundryClient) RefreshTokenWithContext(ctx context.Context) error {
+	for {
+		req.tokenMu.Lock()
+		call := req.refreshCall
+		if call == nil {
+			// no refresh in flight, so this goroutine performs the refresh
+			call = &tokenRefreshCall{done: make(chan struct{})}
+			req.refreshCall = call
+			req.tokenMu.Unlock()
+
+			call.err = req.doRefreshToken(ctx)
+
+			req.tokenMu.Lock()
+			req.refreshCall = nil
+			req.tokenMu.Unlock()
+			close(call.done)
+			return call.err
+		}
+		req.tokenMu.Unlock()
+
+		// wait for the refresh in flight
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-call.done:
+		}
+		// if the refresh was aborted because the context of the refreshing goroutine was cancelled,
+		// the refresh is tried again with our own context
+		if call.err != nil && ctx.Err() == nil &&
+			(errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+			continue
+		}
+		return call.err
+	}
+}
+
+// doRefreshToken requests a new authToken from the TokenSource and stores it
+func (req *CloudFoundryClient) doRefreshToken(ctx context.Context) (err error) {
+	ctx, span := req.telemetry.startSpan(ctx, "cf.RefreshToken", trace.SpanKindInternal)
+	start := time.Now()
+	defer func() {
+		req.telemetry.recordRefresh(ctx, time.Since(start), err)
+		endSpan(span, err)
+	}()
+
+	token, err := tokenFromSource(contextWithClient(ctx, req), req.tokenSource)
 	if err != nil {
 		return err
 	}
 
-	// Update the authentication token and timestamp based on the response
-	req.authToken = resp.Result().(*AuthTokenInfo)
-	req.lastAuthTime = time.Now()
+	// Update the authentication token based on the response
+	req.tokenMu.Lock()
+	defer req.tokenMu.Unlock()
+	tokenCopy := *token
+	req.authToken = &tokenCopy
 	return nil
 }
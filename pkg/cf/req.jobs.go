@@ -0,0 +1,168 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+var (
+	MissingJobLocationErr = errors.New("accepted response without job location")
+)
+
+// JobPollPolicy configures how often WaitForJob polls the state of a job.
+// The interval starts at InitialInterval and is multiplied by Multiplier after every poll until it reaches MaxInterval
+type JobPollPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+}
+
+// DefaultJobPollPolicy returns a poll policy which starts polling after 500ms and backs off to polling every 10s
+func DefaultJobPollPolicy() JobPollPolicy {
+	return JobPollPolicy{
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      1.5,
+	}
+}
+
+// WithJobPollPolicy is a client option that sets the poll policy used by WaitForJob
+func WithJobPollPolicy(policy JobPollPolicy) ClientOption {
+	return func(c *CloudFoundryClient) {
+		c.jobPollPolicy = policy
+	}
+}
+
+// interval returns the time to wait before the given poll (starting at 0)
+func (p JobPollPolicy) interval(poll int) time.Duration {
+	if p == (JobPollPolicy{}) {
+		p = DefaultJobPollPolicy()
+	}
+	interval := float64(p.InitialInterval) * math.Pow(max(p.Multiplier, 1), float64(poll))
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+	return time.Duration(interval)
+}
+
+// JobHandle is a handle to an asynchronous job started by a request which was accepted by the Cloud Controller
+type JobHandle struct {
+	// GUID is the GUID of the job
+	GUID string
+
+	// URL is the URL of the job as returned in the Location header
+	URL string
+
+	req JobsAPI
+}
+
+// NewJobHandle returns a handle to the job with the given GUID and URL which is fetched using jobs,
+// e.g. to return job handles from a mock
+func NewJobHandle(jobs JobsAPI, guid, url string) *JobHandle {
+	return &JobHandle{
+		GUID: guid,
+		URL:  url,
+		req:  jobs,
+	}
+}
+
+// Get fetches the current state of the job
+func (j *JobHandle) Get() (*models.Job, error) {
+	return j.GetWithContext(context.Background())
+}
+
+// GetWithContext is like Get but uses the given context
+func (j *JobHandle) GetWithContext(ctx context.Context) (*models.Job, error) {
+	return j.req.GetJobWithContext(ctx, j.GUID)
+}
+
+// Wait waits until the job is finished. See WaitForJob
+func (j *JobHandle) Wait() (*models.Job, error) {
+	return j.WaitWithContext(context.Background())
+}
+
+// WaitWithContext is like Wait but uses the given context
+func (j *JobHandle) WaitWithContext(ctx context.Context) (*models.Job, error) {
+	return j.req.WaitForJobWithContext(ctx, j.GUID)
+}
+
+// jobFromResponse returns a handle to the job referenced in the Location header of the accepted response
+func (req *CloudFoundryClient) jobFromResponse(resp *resty.Response) (*JobHandle, error) {
+	location := resp.Header().Get("Location")
+	if location == "" {
+		return nil, fmt.Errorf("%w: %s %s", MissingJobLocationErr, resp.Request.Method, resp.Request.URL)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse job location %q: %w", location, err)
+	}
+	return NewJobHandle(req, path.Base(u.Path), location), nil
+}
+
+// DeleteAsync sends a DELETE request for a resource which is deleted asynchronously by the Cloud Controller
+// :param path: The path to the endpoint. This can be a string, AbsolutePath or RelativePath
+// :param modifiers: One or more optional modifiers that will be called with the request object before it is executed
+// :return: A handle to the job deleting the resource
+func (req *CloudFoundryClient) DeleteAsync(path string, modifiers ...RequestModifier) (*JobHandle, error) {
+	return req.DeleteAsyncWithContext(context.Background(), path, modifiers...)
+}
+
+// DeleteAsyncWithContext is like DeleteAsync but uses the given context
+func (req *CloudFoundryClient) DeleteAsyncWithContext(
+	ctx context.Context,
+	path string,
+	modifiers ...RequestModifier,
+) (*JobHandle, error) {
+	resp, err := req.SendRequestWithContext(ctx, resty.MethodDelete, path, modifiers...)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusAccepted {
+		return nil, fmt.Errorf("expected %d, got %d", http.StatusAccepted, resp.StatusCode())
+	}
+	return req.jobFromResponse(resp)
+}
+
+// GetJob fetches an asynchronous job by GUID
+func (req *CloudFoundryClient) GetJob(jobGUID string) (*models.Job, error) {
+	return req.GetJobWithContext(context.Background(), jobGUID)
+}
+
+// GetJobWithContext is like GetJob but uses the given context
+func (req *CloudFoundryClient) GetJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error) {
+	return GetResultWithContext[models.Job](ctx, req, "/v3/jobs/"+jobGUID)
+}
+
+// WaitForJob polls the job with backoff (see JobPollPolicy) until it is either complete or failed.
+// The finished job is returned including its warnings. If the job failed, a *JobFailedError
+// containing the job's errors is returned together with the job
+func (req *CloudFoundryClient) WaitForJob(jobGUID string) (*models.Job, error) {
+	return req.WaitForJobWithContext(context.Background(), jobGUID)
+}
+
+// WaitForJobWithContext is like WaitForJob but uses the given context, which can be used to limit the time to wait
+func (req *CloudFoundryClient) WaitForJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error) {
+	for poll := 0; ; poll++ {
+		job, err := req.GetJobWithContext(ctx, jobGUID)
+		if err != nil {
+			return nil, err
+		}
+		switch job.State {
+		case models.JobStateComplete:
+			return job, nil
+		case models.JobStateFailed:
+			return job, &JobFailedError{Job: job}
+		}
+		if err = sleepContext(ctx, req.jobPollPolicy.interval(poll)); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -1,31 +1,73 @@
 package cf
 
 import (
+	"context"
+	"github.com/darmiel/go-cf-client/pkg/labels"
 	"github.com/darmiel/go-cf-client/pkg/models"
-	"strings"
 )
 
 // ListOrganizationsOptions specifies criteria for fetching organizations,
 // including pagination and optional filtering by names or GUIDFilters.
 type ListOrganizationsOptions struct {
 	PaginationOptions
+	TimestampFilterOptions
 
 	// NameFilters is an optional list of organization names to filter by
 	NameFilters []string
 
 	// GUIDFilters is an optional list of organization GUIDFilters to filter by
 	GUIDFilters []string
+
+	// LabelSelector is an optional label selector to filter by
+	LabelSelector string
+
+	// Labels is a label selector built or parsed by the labels package to filter by.
+	// It is combined with LabelSelector if both are set
+	Labels *labels.Selector
+
+	// OrderBy is an optional value to sort by
+	OrderBy OrderBy
 }
 
 // ListOrganizations fetches a list of organizations based on the provided fetch options,
 // which include pagination and filters by names and GUIDFilters.
 func (req *CloudFoundryClient) ListOrganizations(options ListOrganizationsOptions) ([]models.Organization, error) {
-	queryParams := createParams(options.PaginationOptions)
-	if options.NameFilters != nil {
-		queryParams["names"] = strings.Join(options.NameFilters, ",")
+	return req.ListOrganizationsWithContext(context.Background(), options)
+}
+
+// ListOrganizationsWithContext is like ListOrganizations but uses the given context
+func (req *CloudFoundryClient) ListOrganizationsWithContext(
+	ctx context.Context,
+	options ListOrganizationsOptions,
+) ([]models.Organization, error) {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return nil, err
 	}
-	if options.GUIDFilters != nil {
-		queryParams["guids"] = strings.Join(options.GUIDFilters, ",")
+	return GetPaginatedWithContext[models.Organization](
+		ctx, req, "/v3/organizations", WithListQuery(query),
+	)
+}
+
+// ListOrganizationsIterator returns an iterator which lazily fetches the organizations
+func (req *CloudFoundryClient) ListOrganizationsIterator(
+	options ListOrganizationsOptions,
+) *PageIterator[models.Organization] {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return newFailedPageIterator[models.Organization](err)
 	}
-	return GetPaginated[models.Organization](req, "/v3/organizations", WithQueryParams(queryParams))
+	return GetPageIterator[models.Organization](req, "/v3/organizations", WithListQuery(query))
+}
+
+// query returns the list query for the options
+func (options ListOrganizationsOptions) query() *ListQuery {
+	return NewListQuery().
+		Pagination(options.PaginationOptions).
+		Timestamps(options.TimestampFilterOptions).
+		Names(options.NameFilters...).
+		GUIDs(options.GUIDFilters...).
+		LabelSelector(options.LabelSelector).
+		Labels(options.Labels).
+		OrderBy(options.OrderBy)
 }
@@ -1,10 +1,10 @@
 package cf
 
 import (
+	"context"
 	"fmt"
 	"github.com/darmiel/go-cf-client/internal/util"
 	"github.com/darmiel/go-cf-client/pkg/models"
-	"net/http"
 	"strings"
 )
 
@@ -23,16 +23,6 @@ const (
 	SpaceSupporterRole                  = "space_supporter"
 )
 
-type OrderBy string
-
-//goland:noinspection GoUnusedConst
-const (
-	OrderByCreatedAtAsc  = "created_at"
-	OrderByCreatedAtDesc = "-created_at"
-	OrderByUpdatedAtAsc  = "updated_at"
-	OrderByUpdatedAtDesc = "-updated_at"
-)
-
 var (
 	CreateRoleTargetMissingErr = fmt.Errorf("either UserGUID or Username must be provided")
 	InvalidRoleErr             = fmt.Errorf("invalid role")
@@ -63,6 +53,16 @@ func (req *CloudFoundryClient) CreateRole(
 	role Role,
 	spaceOrOrganizationGUID string,
 	options CreateRoleOptions,
+) (*models.Role, error) {
+	return req.CreateRoleWithContext(context.Background(), role, spaceOrOrganizationGUID, options)
+}
+
+// CreateRoleWithContext is like CreateRole but uses the given context
+func (req *CloudFoundryClient) CreateRoleWithContext(
+	ctx context.Context,
+	role Role,
+	spaceOrOrganizationGUID string,
+	options CreateRoleOptions,
 ) (*models.Role, error) {
 	userData := make(util.KV)
 	if options.UserGUID != "" {
@@ -89,19 +89,49 @@ func (req *CloudFoundryClient) CreateRole(
 		"type":          string(role),
 		"relationships": relationships,
 	}
-	fmt.Println("payload:", data)
-	return PostResult[models.Role](req, "/v3/roles", WithBody(data))
+	return PostResultWithContext[models.Role](ctx, req, "/v3/roles", WithBody(data))
 }
 
 // GetRole fetches a role by GUID
 func (req *CloudFoundryClient) GetRole(roleGUID string) (*models.Role, error) {
-	return GetResult[models.Role](req, "/v3/roles/"+roleGUID)
+	return req.GetRoleWithContext(context.Background(), roleGUID)
+}
+
+// GetRoleWithContext is like GetRole but uses the given context
+func (req *CloudFoundryClient) GetRoleWithContext(ctx context.Context, roleGUID string) (*models.Role, error) {
+	return GetResultWithContext[models.Role](ctx, req, "/v3/roles/"+roleGUID)
+}
+
+// GetRoleEnriched fetches a role by GUID joined with its user, space and organization.
+// If no includes are given, the user, space and organization are included
+func (req *CloudFoundryClient) GetRoleEnriched(roleGUID string, include ...Include) (*models.EnrichedRole, error) {
+	return req.GetRoleEnrichedWithContext(context.Background(), roleGUID, include...)
+}
+
+// GetRoleEnrichedWithContext is like GetRoleEnriched but uses the given context
+func (req *CloudFoundryClient) GetRoleEnrichedWithContext(
+	ctx context.Context,
+	roleGUID string,
+	include ...Include,
+) (*models.EnrichedRole, error) {
+	if len(include) == 0 {
+		include = []Include{IncludeUser, IncludeSpace, IncludeOrganization}
+	}
+	role, included, err := GetResultWithIncludedWithContext[models.Role](
+		ctx, req, "/v3/roles/"+roleGUID, WithInclude(include...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	enriched := included.EnrichRole(*role)
+	return &enriched, nil
 }
 
 // ListRoleOptions specifies criteria for fetching roles,
 // including pagination and optional filtering by type, user GUID, organization GUID, and space GUID
 type ListRoleOptions struct {
 	PaginationOptions
+	TimestampFilterOptions
 
 	// RoleGUIDFilters is an optional list of role GUIDFilters to filter by
 	RoleGUIDFilters []string
@@ -120,33 +150,72 @@ type ListRoleOptions struct {
 
 	// OrderBy is an optional value to sort by
 	OrderBy OrderBy
+
+	// Include is an optional list of related resources (IncludeUser, IncludeSpace, IncludeOrganization)
+	// to return in the same response. Use ListRoleEnriched to join them with the roles
+	Include []Include
 }
 
 // ListRole fetches a list of roles based on the provided fetch options
 func (req *CloudFoundryClient) ListRole(options ListRoleOptions) ([]models.Role, error) {
-	queryParams := createParams(options.PaginationOptions)
-	if options.RoleGUIDFilters != nil {
-		queryParams["guids"] = strings.Join(options.RoleGUIDFilters, ",")
-	}
-	if options.RoleTypeFilters != nil {
-		queryParams["types"] = strings.Join(options.RoleTypeFilters, ",")
-	}
-	if options.SpaceGUIDFilters != nil {
-		queryParams["space_guids"] = strings.Join(options.SpaceGUIDFilters, ",")
-	}
-	if options.OrganizationGUIDFilters != nil {
-		queryParams["organization_guids"] = strings.Join(options.OrganizationGUIDFilters, ",")
-	}
-	if options.UserGUIDFilters != nil {
-		queryParams["user_guids"] = strings.Join(options.UserGUIDFilters, ",")
+	return req.ListRoleWithContext(context.Background(), options)
+}
+
+// ListRoleWithContext is like ListRole but uses the given context
+func (req *CloudFoundryClient) ListRoleWithContext(ctx context.Context, options ListRoleOptions) ([]models.Role, error) {
+	return GetPaginatedWithContext[models.Role](ctx, req, "/v3/roles", WithListQuery(options.query()))
+}
+
+// ListRoleIterator returns an iterator which lazily fetches the roles
+func (req *CloudFoundryClient) ListRoleIterator(options ListRoleOptions) *PageIterator[models.Role] {
+	return GetPageIterator[models.Role](req, "/v3/roles", WithListQuery(options.query()))
+}
+
+// query returns the list query for the options
+func (options ListRoleOptions) query() *ListQuery {
+	return NewListQuery().
+		Pagination(options.PaginationOptions).
+		Timestamps(options.TimestampFilterOptions).
+		GUIDs(options.RoleGUIDFilters...).
+		Filter("types", options.RoleTypeFilters...).
+		Filter("space_guids", options.SpaceGUIDFilters...).
+		Filter("organization_guids", options.OrganizationGUIDFilters...).
+		Filter("user_guids", options.UserGUIDFilters...).
+		OrderBy(options.OrderBy).
+		Include(options.Include...)
+}
+
+// ListRoleEnriched fetches a list of roles joined with their users, spaces and organizations.
+// The related resources are returned by the Cloud Controller in the same responses, so no further requests are sent.
+// If options.Include is empty, users, spaces and organizations are included
+func (req *CloudFoundryClient) ListRoleEnriched(options ListRoleOptions) ([]models.EnrichedRole, error) {
+	return req.ListRoleEnrichedWithContext(context.Background(), options)
+}
+
+// ListRoleEnrichedWithContext is like ListRoleEnriched but uses the given context
+func (req *CloudFoundryClient) ListRoleEnrichedWithContext(
+	ctx context.Context,
+	options ListRoleOptions,
+) ([]models.EnrichedRole, error) {
+	if len(options.Include) == 0 {
+		options.Include = []Include{IncludeUser, IncludeSpace, IncludeOrganization}
 	}
-	if options.OrderBy != "" {
-		queryParams["order_by"] = string(options.OrderBy)
+	roles, included, err := GetPaginatedWithIncludedWithContext[models.Role](
+		ctx, req, "/v3/roles", WithListQuery(options.query()),
+	)
+	if err != nil {
+		return nil, err
 	}
-	return GetPaginated[models.Role](req, "/v3/roles", WithQueryParams(queryParams))
+	return included.EnrichRoles(roles), nil
 }
 
 // DeleteRole deletes a role by GUID
-func (req *CloudFoundryClient) DeleteRole(roleGUID string) error {
-	return req.DeleteAndExpectStatus("/v3/roles/"+roleGUID, http.StatusAccepted)
+// The deletion happens asynchronously, use the returned job handle to wait for it to finish
+func (req *CloudFoundryClient) DeleteRole(roleGUID string) (*JobHandle, error) {
+	return req.DeleteRoleWithContext(context.Background(), roleGUID)
+}
+
+// DeleteRoleWithContext is like DeleteRole but uses the given context
+func (req *CloudFoundryClient) DeleteRoleWithContext(ctx context.Context, roleGUID string) (*JobHandle, error) {
+	return req.DeleteAsyncWithContext(ctx, "/v3/roles/"+roleGUID)
 }
@@ -1,14 +1,16 @@
 package cf
 
 import (
+	"context"
 	"github.com/darmiel/go-cf-client/internal/util"
+	"github.com/darmiel/go-cf-client/pkg/labels"
 	"github.com/darmiel/go-cf-client/pkg/models"
-	"strings"
 )
 
 // ListSpacesOptions are the options for listing spaces
 type ListSpacesOptions struct {
 	PaginationOptions
+	TimestampFilterOptions
 
 	// Names is a list of space names to filter by
 	Names []string
@@ -19,31 +21,111 @@ type ListSpacesOptions struct {
 	// OrganizationGUIDs is a list of organization GUIDs to filter by
 	OrganizationGUIDs []string
 
-	// Labels is a list of space labels to filter by
+	// LabelSelector is a label selector to filter by
 	LabelSelector string
+
+	// Labels is a label selector built or parsed by the labels package to filter by.
+	// It is combined with LabelSelector if both are set
+	Labels *labels.Selector
+
+	// OrderBy is the value to sort by
+	OrderBy OrderBy
+
+	// Include is a list of related resources (IncludeOrganization) to return in the same response.
+	// Use ListSpacesEnriched to join them with the spaces
+	Include []Include
 }
 
 // ListSpaces returns a list of spaces the user has access to
 func (req *CloudFoundryClient) ListSpaces(options ListSpacesOptions) ([]models.Space, error) {
-	params := createParams(options.PaginationOptions)
-	if len(options.Names) != 0 {
-		params["names"] = strings.Join(options.Names, ",")
+	return req.ListSpacesWithContext(context.Background(), options)
+}
+
+// ListSpacesWithContext is like ListSpaces but uses the given context
+func (req *CloudFoundryClient) ListSpacesWithContext(
+	ctx context.Context,
+	options ListSpacesOptions,
+) ([]models.Space, error) {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return nil, err
 	}
-	if len(options.GUIDs) != 0 {
-		params["guids"] = strings.Join(options.GUIDs, ",")
+	return GetPaginatedWithContext[models.Space](ctx, req, "/v3/spaces", WithListQuery(query))
+}
+
+// ListSpacesIterator returns an iterator which lazily fetches the spaces the user has access to
+func (req *CloudFoundryClient) ListSpacesIterator(options ListSpacesOptions) *PageIterator[models.Space] {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return newFailedPageIterator[models.Space](err)
 	}
-	if len(options.OrganizationGUIDs) != 0 {
-		params["organization_guids"] = strings.Join(options.OrganizationGUIDs, ",")
+	return GetPageIterator[models.Space](req, "/v3/spaces", WithListQuery(query))
+}
+
+// query returns the list query for the options
+func (options ListSpacesOptions) query() *ListQuery {
+	return NewListQuery().
+		Pagination(options.PaginationOptions).
+		Timestamps(options.TimestampFilterOptions).
+		Names(options.Names...).
+		GUIDs(options.GUIDs...).
+		Filter("organization_guids", options.OrganizationGUIDs...).
+		LabelSelector(options.LabelSelector).
+		Labels(options.Labels).
+		OrderBy(options.OrderBy).
+		Include(options.Include...)
+}
+
+// ListSpacesEnriched returns a list of spaces the user has access to joined with their organizations.
+// The organizations are returned by the Cloud Controller in the same responses, so no further requests are sent
+func (req *CloudFoundryClient) ListSpacesEnriched(options ListSpacesOptions) ([]models.EnrichedSpace, error) {
+	return req.ListSpacesEnrichedWithContext(context.Background(), options)
+}
+
+// ListSpacesEnrichedWithContext is like ListSpacesEnriched but uses the given context
+func (req *CloudFoundryClient) ListSpacesEnrichedWithContext(
+	ctx context.Context,
+	options ListSpacesOptions,
+) ([]models.EnrichedSpace, error) {
+	options.Include = []Include{IncludeOrganization}
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return nil, err
 	}
-	if options.LabelSelector != "" {
-		params["label_selector"] = options.LabelSelector
+	spaces, included, err := GetPaginatedWithIncludedWithContext[models.Space](
+		ctx, req, "/v3/spaces", WithListQuery(query),
+	)
+	if err != nil {
+		return nil, err
 	}
-	return GetPaginated[models.Space](req, "/v3/spaces", WithQueryParams(params))
+	return included.EnrichSpaces(spaces), nil
 }
 
 // GetSpace returns a space by GUID
 func (req *CloudFoundryClient) GetSpace(guid string) (*models.Space, error) {
-	return GetResult[models.Space](req, "/v3/spaces/"+guid)
+	return req.GetSpaceWithContext(context.Background(), guid)
+}
+
+// GetSpaceWithContext is like GetSpace but uses the given context
+func (req *CloudFoundryClient) GetSpaceWithContext(ctx context.Context, guid string) (*models.Space, error) {
+	return GetResultWithContext[models.Space](ctx, req, "/v3/spaces/"+guid)
+}
+
+// GetSpaceEnriched returns a space by GUID joined with its organization
+func (req *CloudFoundryClient) GetSpaceEnriched(guid string) (*models.EnrichedSpace, error) {
+	return req.GetSpaceEnrichedWithContext(context.Background(), guid)
+}
+
+// GetSpaceEnrichedWithContext is like GetSpaceEnriched but uses the given context
+func (req *CloudFoundryClient) GetSpaceEnrichedWithContext(ctx context.Context, guid string) (*models.EnrichedSpace, error) {
+	space, included, err := GetResultWithIncludedWithContext[models.Space](
+		ctx, req, "/v3/spaces/"+guid, WithInclude(IncludeOrganization),
+	)
+	if err != nil {
+		return nil, err
+	}
+	enriched := included.EnrichSpace(*space)
+	return &enriched, nil
 }
 
 // UpdateSpaceOptions are the options for updating a space
@@ -51,33 +133,30 @@ type UpdateSpaceOptions struct {
 	// Name is the new name of the space
 	Name string
 
-	// Labels is a map of labels to assign to the space
-	Labels map[string]string
-
-	// Annotations is a map of annotations to assign to the space
-	Annotations map[string]string
+	// Metadata contains the labels and annotations to set or delete
+	Metadata *models.MetadataPatch
 }
 
 // UpdateSpace updates a space by GUID
 // You can update the name, labels, and annotations
 func (req *CloudFoundryClient) UpdateSpace(guid string, options UpdateSpaceOptions) (*models.Space, error) {
-	body := map[string]any{}
+	return req.UpdateSpaceWithContext(context.Background(), guid, options)
+}
+
+// UpdateSpaceWithContext is like UpdateSpace but uses the given context
+func (req *CloudFoundryClient) UpdateSpaceWithContext(
+	ctx context.Context,
+	guid string,
+	options UpdateSpaceOptions,
+) (*models.Space, error) {
+	body := util.KV{}
 	if options.Name != "" {
 		body["name"] = options.Name
 	}
-
-	metadata := map[string]any{}
-	if options.Labels != nil {
-		metadata["labels"] = options.Labels
-	}
-	if options.Annotations != nil {
-		metadata["annotations"] = options.Annotations
+	if !options.Metadata.IsEmpty() {
+		body["metadata"] = options.Metadata
 	}
-	if len(metadata) > 0 {
-		body["metadata"] = metadata
-	}
-
-	return PatchResult[models.Space](req, "/v3/spaces/"+guid, WithBody(body))
+	return PatchResultWithContext[models.Space](ctx, req, "/v3/spaces/"+guid, WithBody(body))
 }
 
 // CreateSpaceOptions are the options for creating a space
@@ -92,24 +171,31 @@ type CreateSpaceOptions struct {
 // CreateSpace creates a space with the specified name and organization GUID
 // You can also specify labels and annotations
 func (req *CloudFoundryClient) CreateSpace(name, orgGUID string, options CreateSpaceOptions) (*models.Space, error) {
+	return req.CreateSpaceWithContext(context.Background(), name, orgGUID, options)
+}
+
+// CreateSpaceWithContext is like CreateSpace but uses the given context
+func (req *CloudFoundryClient) CreateSpaceWithContext(
+	ctx context.Context,
+	name, orgGUID string,
+	options CreateSpaceOptions,
+) (*models.Space, error) {
 	body := util.KV{
 		"name": name,
 		"relationships": util.KV{
 			"organization": util.DataGUID(orgGUID),
 		},
 	}
-	if options.Labels != nil {
-		body["labels"] = options.Labels
-	}
-	if options.Annotations != nil {
-		body["annotations"] = options.Annotations
+	if metadata := createMetadata(options.Labels, options.Annotations); metadata != nil {
+		body["metadata"] = metadata
 	}
-	return PostResult[models.Space](req, "/v3/spaces", WithBody(body))
+	return PostResultWithContext[models.Space](ctx, req, "/v3/spaces", WithBody(body))
 }
 
 // ListUsersForSpaceOptions specifies the options for listing users for a space.
 type ListUsersForSpaceOptions struct {
 	PaginationOptions
+	TimestampFilterOptions
 
 	// GUIDFilters is a comma-delimited list of user GUIDFilters to filter by.
 	GUIDFilters []string
@@ -128,19 +214,56 @@ type ListUsersForSpaceOptions struct {
 
 	// LabelSelector contains a list of label selector requirements.
 	LabelSelector string
+
+	// Labels is a label selector built or parsed by the labels package to filter by.
+	// It is combined with LabelSelector if both are set
+	Labels *labels.Selector
 }
 
 // ListUsersForSpace lists all users with a role in the specified space.
 // This method supports filtering by user GUIDFilters, usernames (or partial usernames), origins,
 // as well as pagination and sorting options.
 func (req *CloudFoundryClient) ListUsersForSpace(spaceGUID string, options ListUsersForSpaceOptions) ([]models.User, error) {
-	queryParams := util.CreateQueryParams(util.Query{
-		"guids":             strings.Join(options.GUIDFilters, ","),
-		"usernames":         strings.Join(options.UsernameFilters, ","),
-		"partial_usernames": strings.Join(options.PartialUsernameFilters, ","),
-		"origins":           strings.Join(options.OriginFilters, ","),
-		"order_by":          options.OrderBy,
-		"label_selector":    options.LabelSelector,
-	}, options.PaginationOptions.PerPage)
-	return GetPaginated[models.User](req, "/v3/spaces/"+spaceGUID+"/users", WithQueryParams(queryParams))
+	return req.ListUsersForSpaceWithContext(context.Background(), spaceGUID, options)
+}
+
+// ListUsersForSpaceWithContext is like ListUsersForSpace but uses the given context
+func (req *CloudFoundryClient) ListUsersForSpaceWithContext(
+	ctx context.Context,
+	spaceGUID string,
+	options ListUsersForSpaceOptions,
+) ([]models.User, error) {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return nil, err
+	}
+	return GetPaginatedWithContext[models.User](
+		ctx, req, "/v3/spaces/"+spaceGUID+"/users", WithListQuery(query),
+	)
+}
+
+// ListUsersForSpaceIterator returns an iterator which lazily fetches all users with a role in the specified space
+func (req *CloudFoundryClient) ListUsersForSpaceIterator(
+	spaceGUID string,
+	options ListUsersForSpaceOptions,
+) *PageIterator[models.User] {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return newFailedPageIterator[models.User](err)
+	}
+	return GetPageIterator[models.User](req, "/v3/spaces/"+spaceGUID+"/users", WithListQuery(query))
+}
+
+// query returns the list query for the options
+func (options ListUsersForSpaceOptions) query() *ListQuery {
+	return NewListQuery().
+		Pagination(options.PaginationOptions).
+		Timestamps(options.TimestampFilterOptions).
+		GUIDs(options.GUIDFilters...).
+		Filter("usernames", options.UsernameFilters...).
+		Filter("partial_usernames", options.PartialUsernameFilters...).
+		Filter("origins", options.OriginFilters...).
+		OrderBy(OrderBy(options.OrderBy)).
+		LabelSelector(options.LabelSelector).
+		Labels(options.Labels)
 }
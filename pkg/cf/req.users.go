@@ -1,10 +1,10 @@
 package cf
 
 import (
+	"context"
 	"github.com/darmiel/go-cf-client/internal/util"
+	"github.com/darmiel/go-cf-client/pkg/labels"
 	"github.com/darmiel/go-cf-client/pkg/models"
-	"net/http"
-	"strings"
 )
 
 // CreateUserOptions specifies options for creating a user, including labels and annotations
@@ -22,29 +22,39 @@ type CreateUserOptions struct {
 // CreateUser creates a new user with the specified GUID and options including labels and annotations.
 // It returns the created user or an error if the creation fails.
 func (req *CloudFoundryClient) CreateUser(guid string, options CreateUserOptions) (*models.User, error) {
-	metadata := make(util.KV)
-	if options.Labels != nil {
-		metadata["labels"] = options.Labels
-	}
-	if options.Annotations != nil {
-		metadata["annotations"] = options.Annotations
-	}
+	return req.CreateUserWithContext(context.Background(), guid, options)
+}
+
+// CreateUserWithContext is like CreateUser but uses the given context
+func (req *CloudFoundryClient) CreateUserWithContext(
+	ctx context.Context,
+	guid string,
+	options CreateUserOptions,
+) (*models.User, error) {
 	body := util.KV{
 		"guid": guid,
 	}
-	if len(metadata) > 0 {
+	if metadata := createMetadata(options.Labels, options.Annotations); metadata != nil {
 		body["metadata"] = metadata
 	}
-	return PostResult[models.User](req, "/v3/users", WithBody(body))
+	return PostResultWithContext[models.User](ctx, req, "/v3/users", WithBody(body))
 }
 
 // GetUser fetches a user by their GUID. It returns the user if found or an error otherwise.
 func (req *CloudFoundryClient) GetUser(userGUID string) (*models.User, error) {
-	return GetResult[models.User](req, "/v3/users/"+userGUID)
+	return req.GetUserWithContext(context.Background(), userGUID)
+}
+
+// GetUserWithContext is like GetUser but uses the given context
+func (req *CloudFoundryClient) GetUserWithContext(ctx context.Context, userGUID string) (*models.User, error) {
+	return GetResultWithContext[models.User](ctx, req, "/v3/users/"+userGUID)
 }
 
 // ListUsersOptions specifies options for listing users with various filters.
 type ListUsersOptions struct {
+	PaginationOptions
+	TimestampFilterOptions
+
 	// GUIDFilters is a list of user GUIDFilters to filter by. Providing multiple GUIDFilters
 	// will return users that match any of the specified GUIDFilters.
 	GUIDFilters []string
@@ -74,34 +84,81 @@ type ListUsersOptions struct {
 	// requirements. The syntax of the selector is similar to Kubernetes label
 	// selectors. This allows for filtering users based on a set of labels.
 	LabelSelector string
+
+	// Labels is a label selector built or parsed by the labels package to filter by.
+	// It is combined with LabelSelector if both are set
+	Labels *labels.Selector
 }
 
 // ListUsers lists all users that the current user can see, optionally filtered by the provided
 // ListUsersOptions. It supports filtering by multiple criteria such as GUIDFilters, usernames, partial usernames,
 // origins, and labels, and allows sorting of the results.
 func (req *CloudFoundryClient) ListUsers(options ListUsersOptions) ([]models.User, error) {
-	queryParams := util.CreateQueryParams(util.Query{
-		"guids":             strings.Join(options.GUIDFilters, ","),
-		"usernames":         strings.Join(options.UsernameFilters, ","),
-		"partial_usernames": strings.Join(options.PartialUsernameFilters, ","),
-		"origins":           strings.Join(options.OriginFilters, ","),
-		"order_by":          string(options.OrderBy),
-		"label_selector":    options.LabelSelector,
-	})
-	return GetPaginated[models.User](req, "/v3/users", WithQueryParams(queryParams))
+	return req.ListUsersWithContext(context.Background(), options)
+}
+
+// ListUsersWithContext is like ListUsers but uses the given context
+func (req *CloudFoundryClient) ListUsersWithContext(ctx context.Context, options ListUsersOptions) ([]models.User, error) {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return nil, err
+	}
+	return GetPaginatedWithContext[models.User](ctx, req, "/v3/users", WithListQuery(query))
+}
+
+// ListUsersIterator returns an iterator which lazily fetches all users that the current user can see
+func (req *CloudFoundryClient) ListUsersIterator(options ListUsersOptions) *PageIterator[models.User] {
+	query := options.query()
+	if err := query.Err(); err != nil {
+		return newFailedPageIterator[models.User](err)
+	}
+	return GetPageIterator[models.User](req, "/v3/users", WithListQuery(query))
+}
+
+// query returns the list query for the options
+func (options ListUsersOptions) query() *ListQuery {
+	return NewListQuery().
+		Pagination(options.PaginationOptions).
+		Timestamps(options.TimestampFilterOptions).
+		GUIDs(options.GUIDFilters...).
+		Filter("usernames", options.UsernameFilters...).
+		Filter("partial_usernames", options.PartialUsernameFilters...).
+		Filter("origins", options.OriginFilters...).
+		OrderBy(options.OrderBy).
+		LabelSelector(options.LabelSelector).
+		Labels(options.Labels)
 }
 
 // UpdateUser updates a user's metadata including labels and annotations based on the provided GUID.
+// Labels and annotations which are not part of the patch are left unchanged.
 // It returns the updated user or an error if the update fails.
-func (req *CloudFoundryClient) UpdateUser(guid string, metadata util.KV) (*models.User, error) {
+func (req *CloudFoundryClient) UpdateUser(guid string, metadata *models.MetadataPatch) (*models.User, error) {
+	return req.UpdateUserWithContext(context.Background(), guid, metadata)
+}
+
+// UpdateUserWithContext is like UpdateUser but uses the given context
+func (req *CloudFoundryClient) UpdateUserWithContext(
+	ctx context.Context,
+	guid string,
+	metadata *models.MetadataPatch,
+) (*models.User, error) {
+	if metadata == nil {
+		metadata = &models.MetadataPatch{}
+	}
 	body := util.KV{
 		"metadata": metadata,
 	}
-	return PatchResult[models.User](req, "/v3/users/"+guid, WithBody(body))
+	return PatchResultWithContext[models.User](ctx, req, "/v3/users/"+guid, WithBody(body))
 }
 
 // DeleteUser deletes a user by their GUID, along with all roles associated with them.
-// It returns an error if the deletion fails.
-func (req *CloudFoundryClient) DeleteUser(userGUID string) error {
-	return req.DeleteAndExpectStatus("/v3/users/"+userGUID, http.StatusAccepted)
+// The deletion happens asynchronously, use the returned job handle to wait for it to finish.
+// It returns an error if the deletion could not be started.
+func (req *CloudFoundryClient) DeleteUser(userGUID string) (*JobHandle, error) {
+	return req.DeleteUserWithContext(context.Background(), userGUID)
+}
+
+// DeleteUserWithContext is like DeleteUser but uses the given context
+func (req *CloudFoundryClient) DeleteUserWithContext(ctx context.Context, userGUID string) (*JobHandle, error) {
+	return req.DeleteAsyncWithContext(ctx, "/v3/users/"+userGUID)
 }
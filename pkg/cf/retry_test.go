@@ -0,0 +1,189 @@
+package cf_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// retryPolicy returns a retry policy without jitter which records all attempts
+func retryPolicy(attempts *[]cf.RetryAttempt) cf.RetryPolicy {
+	return cf.RetryPolicy{
+		MaxAttempts:      5,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       4 * time.Millisecond,
+		Multiplier:       2,
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		OnAttempt: func(attempt cf.RetryAttempt) {
+			*attempts = append(*attempts, attempt)
+		},
+	}
+}
+
+// waits returns the waits of the attempts
+func waits(attempts []cf.RetryAttempt) []time.Duration {
+	result := make([]time.Duration, len(attempts))
+	for i, attempt := range attempts {
+		result[i] = attempt.Wait
+	}
+	return result
+}
+
+func TestRetryBacksOffExponentially(t *testing.T) {
+	var attempts []cf.RetryAttempt
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRetryPolicy(retryPolicy(&attempts)))
+
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Status: http.StatusServiceUnavailable, Times: 4})
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("request failed after retries: %v", err)
+	}
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 0}
+	if got := waits(attempts); !slices.Equal(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+	if last := attempts[len(attempts)-1]; last.Attempt != 5 || last.WillRetry || last.StatusCode != http.StatusOK {
+		t.Errorf("last attempt = %+v, want a successful fifth attempt", last)
+	}
+
+	// the last attempt is not retried
+	attempts = nil
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Status: http.StatusServiceUnavailable})
+	var apiErr *cf.APIError
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusServiceUnavailable || len(attempts) != 5 {
+		t.Errorf("err = %v after %d attempts, want a 503 error after 5 attempts", err, len(attempts))
+	}
+}
+
+func TestRetryUsesWaitRequestedByServer(t *testing.T) {
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		want   time.Duration
+	}{
+		{"retry after", http.StatusServiceUnavailable, http.Header{"Retry-After": {"0"}}, 0},
+		{"retry after is capped", http.StatusServiceUnavailable, http.Header{"Retry-After": {"120"}}, 4 * time.Millisecond},
+		{"retry after date", http.StatusTooManyRequests,
+			http.Header{"Retry-After": {time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)}}, 0},
+		{"rate limit reset", http.StatusTooManyRequests,
+			http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {past}}, 0},
+		{"rate limit reset is capped", http.StatusTooManyRequests,
+			http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {future}}, 4 * time.Millisecond},
+		{"remaining rate limit", http.StatusTooManyRequests,
+			http.Header{"X-Ratelimit-Remaining": {"1"}, "X-Ratelimit-Reset": {past}}, time.Millisecond},
+		{"no header", http.StatusTooManyRequests, nil, time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts []cf.RetryAttempt
+			server := newServer(t, 1)
+			client := newClient(t, server, cf.WithRetryPolicy(retryPolicy(&attempts)))
+
+			server.InjectFault(cftest.Fault{Path: "/v3/spaces", Status: tt.status, Header: tt.header, Times: 1})
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if len(attempts) != 2 || attempts[0].Wait != tt.want {
+				t.Errorf("attempts = %+v, want a retry after %v", attempts, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOnlyRetriesIdempotentMethods(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		nonIdempotent bool
+		want          int
+	}{
+		{"GET", http.MethodGet, false, 2},
+		{"POST", http.MethodPost, false, 1},
+		{"POST with RetryNonIdempotent", http.MethodPost, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts []cf.RetryAttempt
+			policy := retryPolicy(&attempts)
+			policy.RetryNonIdempotent = tt.nonIdempotent
+			server := newServer(t, 0)
+			client := newClient(t, server, cf.WithRetryPolicy(policy))
+
+			server.InjectFault(cftest.Fault{Method: tt.method, Path: "/v3/users", Status: http.StatusServiceUnavailable, Times: 1})
+			var err error
+			if tt.method == http.MethodPost {
+				_, err = client.CreateUser("user-guid", cf.CreateUserOptions{})
+			} else {
+				_, err = client.ListUsers(cf.ListUsersOptions{})
+			}
+			if len(attempts) != tt.want {
+				t.Fatalf("got %d attempts, want %d", len(attempts), tt.want)
+			}
+			if succeeded := err == nil; succeeded != (tt.want > 1) {
+				t.Errorf("err = %v after %d attempts", err, len(attempts))
+			}
+		})
+	}
+}
+
+func TestRetryRetriesTransportErrors(t *testing.T) {
+	var attempts []cf.RetryAttempt
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRetryPolicy(retryPolicy(&attempts)))
+
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Abort: true, Times: 2})
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("request failed after retries: %v", err)
+	}
+	if len(attempts) != 3 || attempts[0].Err == nil || !attempts[0].WillRetry {
+		t.Errorf("attempts = %+v, want two retried transport errors", attempts)
+	}
+
+	// other errors of the transport are not caused by the connection and are not retried
+	attempts = nil
+	failing := errors.New("rejected by transport")
+	reject := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/v3/spaces" {
+				return nil, failing
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	client = newClient(t, server, cf.WithRetryPolicy(retryPolicy(&attempts)), cf.WithTransportWrapper(reject))
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.Is(err, failing) {
+		t.Fatalf("err = %v, want the error of the transport", err)
+	}
+	if len(attempts) != 1 || attempts[0].WillRetry {
+		t.Errorf("attempts = %+v, want a single attempt", attempts)
+	}
+}
+
+func TestRetryDoesNotRetryDoneContext(t *testing.T) {
+	var attempts []cf.RetryAttempt
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRetryPolicy(retryPolicy(&attempts)))
+
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListSpacesWithContext(ctx, cf.ListSpacesOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline of the context", err)
+	}
+	for _, attempt := range attempts {
+		if attempt.WillRetry {
+			t.Errorf("attempt %+v is retried, want no retry", attempt)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package cf_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roundTripperFunc is an http.RoundTripper implemented by a function
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+// RoundTrip calls the function
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// spanByName returns the first span with the given name
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return tracetest.SpanStub{}
+}
+
+// attributeValue returns the value of the attribute with the given key
+func attributeValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing(t *testing.T) {
+	// the client uses the global propagator at the time it is created
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var mu sync.Mutex
+	traceparents := map[string]string{}
+	capture := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			mu.Lock()
+			traceparents[r.URL.Path] = r.Header.Get("traceparent")
+			mu.Unlock()
+			return next.RoundTrip(r)
+		})
+	}
+
+	server := newServer(t, 3)
+	client := newClient(t, server, cf.WithTracerProvider(provider), cf.WithTransportWrapper(capture))
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetSpace("missing"); err == nil {
+		t.Fatal("expected an error for a missing space")
+	}
+	spans := exporter.GetSpans()
+
+	refresh := spanByName(t, spans, "cf.RefreshToken")
+	if refresh.SpanKind != trace.SpanKindInternal {
+		t.Errorf("refresh span kind = %v, want internal", refresh.SpanKind)
+	}
+
+	list := spanByName(t, spans, "cf.FetchAllPages")
+	if value, _ := attributeValue(list.Attributes, cf.ResourceTypeKey); value.AsString() != "spaces" {
+		t.Errorf("list resource type = %q, want spaces", value.AsString())
+	}
+	if value, _ := attributeValue(list.Attributes, "cf.pagination.pages"); value.AsInt64() != 1 {
+		t.Errorf("list pages = %d, want 1", value.AsInt64())
+	}
+
+	var requests []tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "HTTP GET" {
+			requests = append(requests, span)
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d request spans, want 2", len(requests))
+	}
+	ok, failed := requests[0], requests[1]
+	if ok.SpanKind != trace.SpanKindClient || ok.Parent.SpanID() != list.SpanContext.SpanID() {
+		t.Errorf("request span has kind %v and parent %v, want a client span of the list", ok.SpanKind, ok.Parent.SpanID())
+	}
+	if value, _ := attributeValue(ok.Attributes, "http.response.status_code"); value.AsInt64() != http.StatusOK {
+		t.Errorf("status code = %d, want 200", value.AsInt64())
+	}
+	if value, _ := attributeValue(ok.Attributes, "http.request.method"); value.AsString() != http.MethodGet {
+		t.Errorf("method = %q, want GET", value.AsString())
+	}
+	if ok.Status.Code == codes.Error {
+		t.Errorf("successful request has error status %v", ok.Status)
+	}
+	if failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("failed request has status %v and %d events, want an error with a recorded exception",
+			failed.Status, len(failed.Events))
+	}
+	if value, _ := attributeValue(failed.Attributes, "error.type"); value.AsString() != "404" {
+		t.Errorf("error type = %q, want 404", value.AsString())
+	}
+
+	// the trace context is propagated to the Cloud Controller and the UAA
+	want := map[string]trace.SpanContext{
+		"/v3/spaces":         ok.SpanContext,
+		"/v3/spaces/missing": failed.SpanContext,
+		"/oauth/token":       refresh.SpanContext,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for path, span := range want {
+		parent := "00-" + span.TraceID().String() + "-"
+		if traceparent := traceparents[path]; len(traceparent) < len(parent) || traceparent[:len(parent)] != parent {
+			t.Errorf("traceparent of %s = %q, want trace %s", path, traceparent, span.TraceID())
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	server := newServer(t, 3)
+	client := newClient(t, server, cf.WithMeterProvider(provider))
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{PaginationOptions: cf.PaginationOptions{PerPage: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	sums := map[string]float64{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch histogram := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, point := range histogram.DataPoints {
+					counts[m.Name] += point.Count
+					sums[m.Name] += point.Sum
+				}
+			case metricdata.Histogram[int64]:
+				for _, point := range histogram.DataPoints {
+					counts[m.Name] += point.Count
+					sums[m.Name] += float64(point.Sum)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		count uint64
+		sum   float64
+	}{
+		{"cf.client.request.duration", 3, -1},
+		{"cf.client.request.retries", 3, 0},
+		{"cf.client.pagination.pages", 1, 3},
+		{"cf.client.token.refresh.duration", 1, -1},
+	}
+	for _, tt := range tests {
+		if counts[tt.name] != tt.count {
+			t.Errorf("%s has %d records, want %d", tt.name, counts[tt.name], tt.count)
+		}
+		if tt.sum >= 0 && sums[tt.name] != tt.sum {
+			t.Errorf("%s has sum %v, want %v", tt.name, sums[tt.name], tt.sum)
+		}
+	}
+}
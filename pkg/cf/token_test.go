@@ -0,0 +1,238 @@
+package cf_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"golang.org/x/oauth2"
+)
+
+// tokenRequest is a token request sent to the UAA
+type tokenRequest struct {
+	// params are the grant parameters of the query and the form body
+	params url.Values
+
+	clientID     string
+	clientSecret string
+}
+
+// tokenRecorder records the token requests of a client
+type tokenRecorder struct {
+	mu       sync.Mutex
+	requests []tokenRequest
+}
+
+// wrap is a transport wrapper which records all token requests
+func (rec *tokenRecorder) wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path != "/oauth/token" {
+			return next.RoundTrip(r)
+		}
+		request := tokenRequest{params: r.URL.Query()}
+		request.clientID, request.clientSecret, _ = r.BasicAuth()
+		if r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				return nil, err
+			}
+			for key, values := range form {
+				request.params[key] = append(request.params[key], values...)
+			}
+		}
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, request)
+		rec.mu.Unlock()
+		return next.RoundTrip(r)
+	})
+}
+
+// last returns the last recorded token request and fails if there is none
+func (rec *tokenRecorder) last(t *testing.T) tokenRequest {
+	t.Helper()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.requests) == 0 {
+		t.Fatal("no token request was sent")
+	}
+	return rec.requests[len(rec.requests)-1]
+}
+
+// assertParams fails if one of the wanted parameters has a different value.
+// An empty wanted value checks that the parameter is absent or empty
+func assertParams(t *testing.T, params url.Values, want map[string]string) {
+	t.Helper()
+	for key, value := range want {
+		if got := params.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestPasswordGrantRequest(t *testing.T) {
+	var recorder tokenRecorder
+	server := newServer(t, 1, cftest.WithOAuthClient("cf", "client-secret"))
+	newClient(t, server, cf.WithTransportWrapper(recorder.wrap))
+
+	request := recorder.last(t)
+	assertParams(t, request.params, map[string]string{
+		"grant_type": "password",
+		"username":   cftest.DefaultUsername,
+		"password":   cftest.DefaultPassword,
+		"login_hint": "",
+	})
+	if request.clientID != "cf" || request.clientSecret != "client-secret" {
+		t.Errorf("client = %q:%q, want the OAuth client of the config", request.clientID, request.clientSecret)
+	}
+}
+
+func TestTokenSourceForGrantType(t *testing.T) {
+	grants := []cf.GrantType{"", cf.PasswordGrant, cf.ClientCredentialsGrant, cf.PasscodeGrant, cf.JWTBearerGrant}
+	for _, grant := range grants {
+		config := &cf.CloudFoundryConfig{GrantType: grant}
+		if source, err := config.TokenSource(); err != nil || source == nil {
+			t.Errorf("TokenSource() for %q = %v, %v, want a token source", grant, source, err)
+		}
+	}
+
+	server := newServer(t, 1)
+	config := server.Config()
+	config.GrantType = "implicit"
+	if _, err := config.TokenSource(); !errors.Is(err, cf.UnsupportedGrantErr) {
+		t.Errorf("err = %v, want UnsupportedGrantErr", err)
+	}
+	if _, err := config.NewClient(); !errors.Is(err, cf.UnsupportedGrantErr) {
+		t.Errorf("err = %v, want UnsupportedGrantErr", err)
+	}
+}
+
+func TestCustomTokenSource(t *testing.T) {
+	server := newServer(t, 1)
+	token, err := server.Config().PasswordTokenSource().Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the client uses the token of the source instead of requesting its own
+	client, err := server.Config().NewClient(cf.WithTokenSource(oauth2.StaticTokenSource(token)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.GetTokenInfo().AccessToken; got != token.AccessToken {
+		t.Errorf("access token = %q, want the token of the source", got)
+	}
+	assertGrants(t, server, "password")
+}
+
+func TestClientCredentialsGrantRequest(t *testing.T) {
+	var recorder tokenRecorder
+	server := newServer(t, 1, cftest.WithOAuthClient("automation", "client-secret"))
+	config := server.Config()
+	config.GrantType = cf.ClientCredentialsGrant
+	config.Username, config.Password = "", ""
+	client, err := config.NewClient(cf.WithTransportWrapper(recorder.wrap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := recorder.last(t)
+	assertParams(t, request.params, map[string]string{
+		"grant_type": "client_credentials",
+		"username":   "",
+		"password":   "",
+	})
+	if request.clientID != "automation" || request.clientSecret != "client-secret" {
+		t.Errorf("client = %q:%q, want the OAuth client of the config", request.clientID, request.clientSecret)
+	}
+	if token := client.GetTokenInfo(); token.RefreshToken != "" {
+		t.Errorf("refresh token = %q, want none for the client credentials grant", token.RefreshToken)
+	}
+
+	// without a refresh token, the client credentials are used again
+	server.ExpireTokens()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertGrants(t, server, "client_credentials", "client_credentials")
+	assertParams(t, recorder.last(t).params, map[string]string{"grant_type": "client_credentials"})
+}
+
+func TestPasscodeGrantRequest(t *testing.T) {
+	var recorder tokenRecorder
+	server := newServer(t, 1, cftest.WithPasscode("one-time-passcode"))
+	config := server.Config()
+	config.GrantType = cf.PasscodeGrant
+	config.Passcode = "one-time-passcode"
+	config.Origin = "ldap"
+	client, err := config.NewClient(cf.WithTransportWrapper(recorder.wrap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertParams(t, recorder.last(t).params, map[string]string{
+		"grant_type": "password",
+		"passcode":   "one-time-passcode",
+		"login_hint": `{"origin":"ldap"}`,
+		"username":   "",
+		"password":   "",
+	})
+
+	// the passcode is only sent once, further tokens require the refresh token
+	server.RevokeTokens()
+	for range 2 {
+		if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.Is(err, cf.ReauthenticationRequiredErr) {
+			t.Errorf("err = %v, want ReauthenticationRequiredErr", err)
+		}
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	var passcodes int
+	for _, request := range recorder.requests {
+		if request.params.Has("passcode") {
+			passcodes++
+		}
+	}
+	if passcodes != 1 {
+		t.Errorf("the passcode was sent %d times, want once", passcodes)
+	}
+}
+
+func TestJWTBearerGrantRequest(t *testing.T) {
+	var recorder tokenRecorder
+	server := newServer(t, 1, cftest.WithJWTAssertion("header.payload.signature"))
+	config := server.Config()
+	config.GrantType = cf.JWTBearerGrant
+	config.JWTAssertion = "header.payload.signature"
+	config.Origin = "corporate-idp"
+	client, err := config.NewClient(cf.WithTransportWrapper(recorder.wrap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertParams(t, recorder.last(t).params, map[string]string{
+		"grant_type": string(cf.JWTBearerGrant),
+		"assertion":  "header.payload.signature",
+		"client_id":  cftest.DefaultClientID,
+		"login_hint": `{"origin":"corporate-idp"}`,
+		"password":   "",
+	})
+
+	// unlike a passcode, the assertion can be exchanged again if the refresh token is revoked
+	server.RevokeTokens()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertGrants(t, server, string(cf.JWTBearerGrant), string(cf.JWTBearerGrant))
+}
@@ -0,0 +1,316 @@
+package cf_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// tlsFrontend is a TLS server in front of a fake Cloud Controller which records the requests it receives
+type tlsFrontend struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	userAgents  map[string]string
+	connections int
+}
+
+// newTLSFrontend starts a TLS server which forwards all requests to the server.
+// If clientCA is not nil, clients must present a certificate signed by it
+func newTLSFrontend(t *testing.T, server *cftest.Server, clientCA *x509.Certificate) *tlsFrontend {
+	t.Helper()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &tlsFrontend{userAgents: make(map[string]string)}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	f.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.userAgents[r.URL.Path] = r.UserAgent()
+		f.mu.Unlock()
+		proxy.ServeHTTP(w, r)
+	}))
+	// the TLS handshake errors of the failing test cases are expected
+	f.Config.ErrorLog = log.New(io.Discard, "", 0)
+	f.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			f.mu.Lock()
+			f.connections++
+			f.mu.Unlock()
+		}
+	}
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA)
+		f.TLS = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	}
+	f.StartTLS()
+	t.Cleanup(f.Close)
+	return f
+}
+
+// config returns the config of the server with all endpoints pointing to the frontend
+func (f *tlsFrontend) config(server *cftest.Server) *cf.CloudFoundryConfig {
+	config := server.Config()
+	config.APIEndpoint = f.URL
+	config.AuthEndpoint = f.URL
+	config.UAAEndpoint = f.URL
+	return config
+}
+
+// caPEM returns the PEM encoded certificate of the frontend
+func (f *tlsFrontend) caPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.Certificate().Raw})
+}
+
+// newClientCertificate returns a self-signed client certificate and writes it and its key to PEM encoded files
+func newClientCertificate(t *testing.T) (cert tls.Certificate, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if cert, err = tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestTransportTLS(t *testing.T) {
+	server := newServer(t, 1)
+	cert, certFile, keyFile := newClientCertificate(t)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontend := newTLSFrontend(t, server, nil)
+	mTLSFrontend := newTLSFrontend(t, server, leaf)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, frontend.caPEM(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// both frontends use the same certificate of httptest
+	trustFrontend := cf.WithCACertificates(frontend.caPEM())
+	var verificationErr *tls.CertificateVerificationError
+	tests := []struct {
+		name              string
+		frontend          *tlsFrontend
+		skipSSLValidation bool
+		options           []cf.ClientOption
+		// wantErr checks the error of NewClient
+		wantErr func(err error) bool
+	}{
+		{
+			name:     "untrusted certificate",
+			frontend: frontend,
+			wantErr:  func(err error) bool { return errors.As(err, &verificationErr) },
+		},
+		{
+			name:     "CA bundle",
+			frontend: frontend,
+			options:  []cf.ClientOption{trustFrontend},
+		},
+		{
+			name:     "CA file",
+			frontend: frontend,
+			options:  []cf.ClientOption{cf.WithCAFile(caFile)},
+		},
+		{
+			name:              "skip SSL validation",
+			frontend:          frontend,
+			skipSSLValidation: true,
+		},
+		{
+			name:     "CA bundle without certificate",
+			frontend: frontend,
+			options:  []cf.ClientOption{cf.WithCACertificates([]byte("not a certificate"))},
+			wantErr:  func(err error) bool { return errors.Is(err, cf.InvalidTransportErr) },
+		},
+		{
+			name:     "missing CA file",
+			frontend: frontend,
+			options:  []cf.ClientOption{cf.WithCAFile(filepath.Join(t.TempDir(), "missing.pem"))},
+			wantErr:  func(err error) bool { return errors.Is(err, cf.InvalidTransportErr) },
+		},
+		{
+			name:     "missing client certificate",
+			frontend: mTLSFrontend,
+			options:  []cf.ClientOption{trustFrontend},
+			wantErr:  func(err error) bool { return err != nil && !errors.Is(err, cf.InvalidTransportErr) },
+		},
+		{
+			name:     "client certificate",
+			frontend: mTLSFrontend,
+			options:  []cf.ClientOption{trustFrontend, cf.WithClientCertificate(cert)},
+		},
+		{
+			name:     "client certificate file",
+			frontend: mTLSFrontend,
+			options:  []cf.ClientOption{trustFrontend, cf.WithClientCertificateFile(certFile, keyFile)},
+		},
+		{
+			name:     "invalid client certificate file",
+			frontend: mTLSFrontend,
+			options:  []cf.ClientOption{trustFrontend, cf.WithClientCertificateFile(keyFile, certFile)},
+			wantErr:  func(err error) bool { return errors.Is(err, cf.InvalidTransportErr) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.frontend.config(server)
+			config.SkipSSLValidation = tt.skipSSLValidation
+			client, err := config.NewClient(tt.options...)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("err = %v, want a different error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(&httputil.ReverseProxy{
+		// requests to a proxy contain the absolute URL of the target
+		Rewrite: func(*httputil.ProxyRequest) {
+			proxied.Add(1)
+		},
+	})
+	t.Cleanup(proxy.Close)
+	server := newServer(t, 1)
+
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr error
+	}{
+		{"http proxy", proxy.URL, nil},
+		{"unsupported scheme", "ftp://proxy.example.com:21", cf.InvalidTransportErr},
+		{"missing host", "socks5://", cf.InvalidTransportErr},
+		{"invalid URL", "http://proxy.example.com:port", cf.InvalidTransportErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxied.Store(0)
+			client, err := server.NewClient(cf.WithProxy(tt.proxy))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := proxied.Load(); got != 2 {
+				t.Errorf("%d requests were sent through the proxy, want the token request and the list request", got)
+			}
+		})
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server,
+		cf.WithTimeout(50*time.Millisecond),
+		cf.WithRetryPolicy(cf.RetryPolicy{MaxAttempts: 1}))
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Delay: time.Second})
+
+	start := time.Now()
+	_, err := client.ListSpaces(cf.ListSpacesOptions{})
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("request took %v, want it to time out after 50ms", elapsed)
+	}
+}
+
+func TestTransportIsSharedByUAAAndCloudController(t *testing.T) {
+	server := newServer(t, 1)
+	frontend := newTLSFrontend(t, server, nil)
+	client, err := frontend.config(server).NewClient(
+		cf.WithCACertificates(frontend.caPEM()),
+		cf.WithUserAgent("my-tool/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	frontend.mu.Lock()
+	defer frontend.mu.Unlock()
+	for _, path := range []string{"/oauth/token", "/v3/spaces"} {
+		if got := frontend.userAgents[path]; got != "my-tool/1.0" {
+			t.Errorf("User-Agent of %s = %q, want my-tool/1.0", path, got)
+		}
+	}
+	// the token request and the API request are sent over the same connection of the shared transport
+	if frontend.connections != 1 {
+		t.Errorf("%d connections were opened, want 1", frontend.connections)
+	}
+}
@@ -0,0 +1,367 @@
+package cftest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/internal/redact"
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mode is the mode of a Recorder
+type Mode int
+
+//goland:noinspection GoUnusedConst
+const (
+	// ModeReplay replays the recorded interactions without sending any request.
+	// Requests which have not been recorded fail with UnrecordedInteractionErr
+	ModeReplay Mode = iota
+	// ModeRecord sends all requests and records them. Existing interactions of the cassette are replaced
+	ModeRecord
+	// ModeReplayOrRecord replays the recorded interactions and sends and records all other requests.
+	// If the cassette does not exist yet, all requests are recorded
+	ModeReplayOrRecord
+)
+
+// scrubbed replaces the values of sensitive headers, query parameters and JSON fields in cassettes
+const scrubbed = "[SCRUBBED]"
+
+// scrubbedGUIDPrefix is the prefix of scrubbed GUIDs. GUIDs with this prefix are not scrubbed again
+const scrubbedGUIDPrefix = "00000000-"
+
+var (
+	// UnrecordedInteractionErr is returned by the transport of a Recorder if a request
+	// has not been recorded and cannot be sent in the mode of the recorder
+	UnrecordedInteractionErr = errors.New("interaction has not been recorded")
+
+	// guidPattern matches GUIDs in paths, query parameters, headers and bodies
+	guidPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+// Cassette contains the recorded interactions of a Recorder. It is stored as JSON
+type Cassette struct {
+	// ScrubbedGUIDs is true if the GUIDs of the interactions have been scrubbed
+	ScrubbedGUIDs bool          `json:"scrubbed_guids,omitempty"`
+	Interactions  []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a sanitized request of an Interaction.
+// Requests are matched on their Method, Path and normalized Query
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a sanitized response of an Interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder records the HTTP interactions of a client to a cassette file and replays them,
+// so integration tests run deterministically and without a foundation once they have been recorded:
+//
+//	recorder, err := cftest.NewRecorder("testdata/spaces.json", cftest.ModeReplayOrRecord)
+//	defer recorder.Save()
+//
+//	client, err := cfg.NewClient(recorder.ClientOption())
+//
+// Tokens, passwords and other credentials are always scrubbed from the cassette, GUIDs optionally
+// (see WithScrubbedGUIDs). As the requests are sanitized the same way before they are matched,
+// the credentials of the config do not need to be valid while replaying.
+// A Recorder is safe for concurrent use by multiple goroutines
+type Recorder struct {
+	path       string
+	mode       Mode
+	scrubGUIDs bool
+
+	// mu guards all fields below
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+	changed  bool
+}
+
+// RecorderOption configures a Recorder
+type RecorderOption func(r *Recorder)
+
+// WithScrubbedGUIDs is a recorder option that replaces all GUIDs of recorded interactions with stable placeholders.
+// The placeholder of a GUID is derived from the GUID, so requests for GUIDs known to the test still match
+// while replaying, and GUIDs taken from replayed responses are used as they are
+func WithScrubbedGUIDs() RecorderOption {
+	return func(r *Recorder) {
+		r.scrubGUIDs = true
+	}
+}
+
+// NewRecorder returns a recorder for the cassette at the given path.
+// In ModeReplay the cassette must exist, in ModeReplayOrRecord it is loaded if it exists.
+// Recorded interactions are only written to the cassette by Save
+func NewRecorder(path string, mode Mode, options ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	for _, option := range options {
+		option(r)
+	}
+	if mode != ModeRecord {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &r.cassette); err != nil {
+				return nil, fmt.Errorf("cannot parse cassette %s: %w", path, err)
+			}
+		case mode == ModeReplayOrRecord && errors.Is(err, os.ErrNotExist):
+		default:
+			return nil, fmt.Errorf("cannot read cassette: %w", err)
+		}
+	}
+	// once a single interaction has scrubbed GUIDs, all requests must be scrubbed before they are matched
+	r.cassette.ScrubbedGUIDs = r.cassette.ScrubbedGUIDs || r.scrubGUIDs
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// ClientOption returns a client option which sends all requests of the client through the recorder
+func (r *Recorder) ClientOption() cf.ClientOption {
+	return cf.WithTransportWrapper(r.Wrap)
+}
+
+// Wrap returns a transport which replays or records the requests according to the mode of the recorder.
+// Requests which are recorded are sent using the next transport
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &recorderTransport{recorder: r, next: next}
+}
+
+// Cassette returns a copy of the current cassette including the interactions recorded so far
+func (r *Recorder) Cassette() Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cassette := r.cassette
+	cassette.Interactions = slices.Clone(r.cassette.Interactions)
+	return cassette
+}
+
+// Save writes the cassette if interactions have been recorded. Missing directories are created
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.changed {
+		return nil
+	}
+	// HTML escaping is disabled to keep the recorded URLs and bodies readable
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.cassette); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("cannot create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("cannot write cassette: %w", err)
+	}
+	r.changed = false
+	return nil
+}
+
+// recorderTransport is the transport returned by Recorder.Wrap
+type recorderTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+// RoundTrip replays the first matching interaction which has not been replayed yet or records the request
+func (t *recorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.recorder
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	recorded := r.sanitizeRequest(req, body)
+
+	r.mu.Lock()
+	interaction, replayErr := r.replayLocked(recorded)
+	r.mu.Unlock()
+	if interaction != nil {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return interaction.Response.response(req), nil
+	}
+	if r.mode == ModeReplay {
+		return nil, replayErr
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: r.sanitizeResponse(resp, respBody),
+	})
+	r.replayed = append(r.replayed, true)
+	r.changed = true
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replayLocked returns the first interaction matching the request which has not been replayed yet
+// and marks it as replayed. If no interaction matches, an error describing the request is returned.
+// r.mu must be held by the caller
+func (r *Recorder) replayLocked(request RecordedRequest) (*Interaction, error) {
+	matching := 0
+	for i := range r.cassette.Interactions {
+		interaction := &r.cassette.Interactions[i]
+		if !interaction.Request.matches(request) {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return interaction, nil
+		}
+		matching++
+	}
+	err := fmt.Errorf("%w in cassette %s: %s %s", UnrecordedInteractionErr, r.path, request.Method, request.Path)
+	if request.Query != "" {
+		err = fmt.Errorf("%w?%s", err, request.Query)
+	}
+	if matching > 0 {
+		err = fmt.Errorf("%w (all %d matching interactions have already been replayed)", err, matching)
+	}
+	return nil, err
+}
+
+// matches returns true if both requests have the same method, path and normalized query
+func (req RecordedRequest) matches(other RecordedRequest) bool {
+	return req.Method == other.Method && req.Path == other.Path && req.Query == other.Query
+}
+
+// response returns the recorded response as a response to the given request
+func (resp RecordedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// sanitizeRequest returns the request with all sensitive values (and GUIDs if enabled) scrubbed
+// and its query normalized
+func (r *Recorder) sanitizeRequest(req *http.Request, body []byte) RecordedRequest {
+	query := req.URL.Query()
+	redact.Values(query, scrubbed)
+	for _, values := range query {
+		for i, value := range values {
+			values[i] = r.replaceGUIDs(value)
+		}
+	}
+	return RecordedRequest{
+		Method: req.Method,
+		Path:   r.replaceGUIDs(req.URL.Path),
+		// Encode sorts the parameters by key
+		Query:  query.Encode(),
+		Header: r.sanitizeHeader(req.Header),
+		Body:   r.sanitizeBody(req.Header.Get("Content-Type"), body),
+	}
+}
+
+// sanitizeResponse returns the response with all sensitive values (and GUIDs if enabled) scrubbed
+func (r *Recorder) sanitizeResponse(resp *http.Response, body []byte) RecordedResponse {
+	return RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     r.sanitizeHeader(resp.Header),
+		Body:       r.sanitizeBody(resp.Header.Get("Content-Type"), body),
+	}
+}
+
+// sanitizeHeader returns a copy of the header with all sensitive headers scrubbed.
+// The Content-Length is removed, as scrubbing changes the length of the bodies
+func (r *Recorder) sanitizeHeader(header http.Header) http.Header {
+	result := redact.Header(header, scrubbed)
+	result.Del("Content-Length")
+	for _, values := range result {
+		for i, value := range values {
+			values[i] = r.replaceGUIDs(value)
+		}
+	}
+	return result
+}
+
+// sanitizeBody scrubs all sensitive fields of JSON and form bodies. Other bodies are only scrubbed of GUIDs
+func (r *Recorder) sanitizeBody(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			redact.Values(form, scrubbed)
+			body = []byte(form.Encode())
+		}
+	} else {
+		body, _ = redact.JSON(body, scrubbed)
+	}
+	return r.replaceGUIDs(string(body))
+}
+
+// replaceGUIDs replaces all GUIDs of the value with placeholders if the cassette has scrubbed GUIDs
+func (r *Recorder) replaceGUIDs(value string) string {
+	if !r.cassette.ScrubbedGUIDs {
+		return value
+	}
+	return guidPattern.ReplaceAllStringFunc(value, func(guid string) string {
+		if strings.HasPrefix(guid, scrubbedGUIDPrefix) {
+			return guid
+		}
+		sum := sha256.Sum256([]byte(strings.ToLower(guid)))
+		hash := hex.EncodeToString(sum[:12])
+		return scrubbedGUIDPrefix + hash[0:4] + "-" + hash[4:8] + "-" + hash[8:12] + "-" + hash[12:24]
+	})
+}
+
+// readBody reads the body and replaces it with a reader of the read content, so it can be read again
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
@@ -0,0 +1,158 @@
+package cftest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+)
+
+// newRecorder returns a recorder for the cassette at the path
+func newRecorder(t *testing.T, path string, mode cftest.Mode, options ...cftest.RecorderOption) *cftest.Recorder {
+	t.Helper()
+	recorder, err := cftest.NewRecorder(path, mode, options...)
+	if err != nil {
+		t.Fatalf("cannot create recorder: %v", err)
+	}
+	return recorder
+}
+
+// record lists the spaces of a new server through a recorder and saves the cassette.
+// It returns the config of the closed server and the token of the recording client
+func record(t *testing.T, path string, options ...cftest.RecorderOption) (*cf.CloudFoundryConfig, cf.AuthTokenInfo) {
+	t.Helper()
+	server := newServer(t, 3,
+		cftest.WithUser("recorder", "recorder-password"),
+		cftest.WithOAuthClient("recorder-client", "recorder-secret"))
+	recorder := newRecorder(t, path, cftest.ModeRecord, options...)
+	client := newClient(t, server, recorder.ClientOption())
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{OrganizationGUIDs: []string{orgGUID}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("cannot save cassette: %v", err)
+	}
+	cfg := server.Config()
+	server.Close()
+	return cfg, client.GetTokenInfo()
+}
+
+func TestRecorderReplaysRecordedInteractions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "spaces.json")
+	cfg, _ := record(t, path)
+
+	// the server is closed, so all responses are replayed from the cassette
+	recorder := newRecorder(t, path, cftest.ModeReplay)
+	client, err := cfg.NewClient(recorder.ClientOption())
+	if err != nil {
+		t.Fatalf("cannot create client from the cassette: %v", err)
+	}
+	spaces, err := client.ListSpaces(cf.ListSpacesOptions{OrganizationGUIDs: []string{orgGUID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(names(spaces)); got != "[space-1 space-2 space-3]" {
+		t.Errorf("spaces = %s, want [space-1 space-2 space-3]", got)
+	}
+	if spaces[0].Relationships.Organization.Data.Guid != orgGUID {
+		t.Errorf("organization = %s, want %s", spaces[0].Relationships.Organization.Data.Guid, orgGUID)
+	}
+
+	// every interaction is only replayed once
+	_, err = client.ListSpaces(cf.ListSpacesOptions{OrganizationGUIDs: []string{orgGUID}})
+	if !errors.Is(err, cftest.UnrecordedInteractionErr) || !strings.Contains(err.Error(), "already been replayed") {
+		t.Errorf("err = %v, want an already replayed interaction", err)
+	}
+	_, err = client.ListSpaces(cf.ListSpacesOptions{Names: []string{"space-1"}})
+	if !errors.Is(err, cftest.UnrecordedInteractionErr) {
+		t.Errorf("err = %v, want an unrecorded interaction", err)
+	}
+}
+
+func TestRecorderScrubsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spaces.json")
+	cfg, tokenInfo := record(t, path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(data)
+	secrets := map[string]string{
+		"password":      cfg.Password,
+		"client secret": cfg.OAuthClientSecret,
+		"access token":  tokenInfo.AccessToken,
+		"refresh token": tokenInfo.RefreshToken,
+	}
+	for name, secret := range secrets {
+		if secret == "" {
+			t.Fatalf("%s is empty", name)
+		}
+		if strings.Contains(cassette, secret) {
+			t.Errorf("cassette contains the %s %q", name, secret)
+		}
+	}
+
+	interactions := newRecorder(t, path, cftest.ModeReplay).Cassette().Interactions
+	if len(interactions) != 2 {
+		t.Fatalf("got %d interactions, want the token request and the list request", len(interactions))
+	}
+	token, list := interactions[0], interactions[1]
+	for _, request := range []cftest.RecordedRequest{token.Request, list.Request} {
+		if got := request.Header.Get("Authorization"); got != "[SCRUBBED]" {
+			t.Errorf("Authorization header of %s = %q, want it scrubbed", request.Path, got)
+		}
+	}
+	query, err := url.ParseQuery(token.Request.Query)
+	if err != nil || query.Get("password") != "[SCRUBBED]" || query.Get("username") != "recorder" {
+		t.Errorf("token query = %s, want a scrubbed password", token.Request.Query)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(token.Response.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["access_token"] != "[SCRUBBED]" || body["refresh_token"] != "[SCRUBBED]" || body["token_type"] != "bearer" {
+		t.Errorf("token response = %v, want scrubbed tokens", body)
+	}
+	if !strings.Contains(list.Request.Query, orgGUID) {
+		t.Errorf("list query = %s, want the GUIDs to be kept", list.Request.Query)
+	}
+}
+
+func TestRecorderScrubsGUIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spaces.json")
+	cfg, _ := record(t, path, cftest.WithScrubbedGUIDs())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), orgGUID) {
+		t.Errorf("cassette contains the GUID %s", orgGUID)
+	}
+
+	// requests for the original GUID match the scrubbed interactions
+	recorder := newRecorder(t, path, cftest.ModeReplay)
+	if !recorder.Cassette().ScrubbedGUIDs {
+		t.Fatal("cassette does not have scrubbed GUIDs")
+	}
+	client, err := cfg.NewClient(recorder.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaces, err := client.ListSpaces(cf.ListSpacesOptions{OrganizationGUIDs: []string{orgGUID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guid := spaces[0].Relationships.Organization.Data.Guid
+	if guid == orgGUID || !strings.HasPrefix(guid, "00000000-") {
+		t.Errorf("organization = %s, want a scrubbed placeholder", guid)
+	}
+}
@@ -0,0 +1,250 @@
+// Package cftest provides an in-memory fake of the Cloud Controller and the UAA for testing code
+// which uses the cf package without a real foundation.
+//
+// A Server serves both the UAA token endpoint and the Cloud Controller V3 API for organizations, spaces,
+// users and roles, including pagination, filters, label selectors, include side-loading,
+// CF-style error responses and asynchronous jobs for deletions:
+//
+//	server := cftest.NewServer(cftest.WithFixtures(cftest.Fixtures{
+//		Organizations: []models.Organization{{Guid: "org-guid", Name: "my-org"}},
+//	}))
+//	defer server.Close()
+//
+//	client, err := server.NewClient()
+package cftest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+//goland:noinspection GoUnusedConst
+const (
+	// DefaultUsername is the username of the UAA user if no user is configured using WithUser
+	DefaultUsername = "admin"
+	// DefaultPassword is the password of the UAA user if no user is configured using WithUser
+	DefaultPassword = "admin"
+	// DefaultClientID is the OAuth client of the UAA if no client is configured using WithOAuthClient
+	DefaultClientID = "cf"
+	// DefaultTokenExpiry is the lifetime of access tokens if no expiry is configured using WithTokenExpiry
+	DefaultTokenExpiry = time.Hour
+)
+
+// Server is a fake Cloud Controller and UAA served by an httptest.Server.
+// It is safe for concurrent use by multiple goroutines
+type Server struct {
+	// URL is the base URL of the server, which is both the API and the auth endpoint
+	URL string
+
+	server *httptest.Server
+	mux    *http.ServeMux
+
+	// mu guards all fields below
+	mu sync.Mutex
+
+	clientID      string
+	clientSecret  string
+	users         []credentials
+	passcodes     map[string]struct{}
+	assertions    map[string]struct{}
+	tokenExpiry   time.Duration
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]struct{}
+	grants        []string
+
+	organizations []models.Organization
+	spaces        []models.Space
+	ccUsers       []models.User
+	roles         []models.Role
+	jobs          map[string]*job
+	jobPolls      int
+	jobFailures   []models.JobError
+
+	faults []*Fault
+}
+
+// credentials are the credentials of a UAA user
+type credentials struct {
+	username string
+	password string
+}
+
+// Option configures a Server
+type Option func(s *Server)
+
+// WithUser is a server option that adds a UAA user which can authenticate using the password grant.
+// The first user is used by Config. If no user is added, the server has a single user
+// with DefaultUsername and DefaultPassword
+func WithUser(username, password string) Option {
+	return func(s *Server) {
+		s.users = append(s.users, credentials{username: username, password: password})
+	}
+}
+
+// WithPasscode is a server option that adds a one-time passcode which can be used once with the password grant
+// to authenticate as the first user, like the passcodes of the login server's /passcode page
+func WithPasscode(passcode string) Option {
+	return func(s *Server) {
+		s.passcodes[passcode] = struct{}{}
+	}
+}
+
+// WithJWTAssertion is a server option that adds a JWT of a trusted identity provider which can be exchanged
+// for tokens using the JWT bearer grant. The assertion isn't validated, it is only compared to the added assertions
+func WithJWTAssertion(assertion string) Option {
+	return func(s *Server) {
+		s.assertions[assertion] = struct{}{}
+	}
+}
+
+// WithOAuthClient is a server option that sets the OAuth client which is accepted by the UAA.
+// Defaults to DefaultClientID with an empty secret
+func WithOAuthClient(clientID, clientSecret string) Option {
+	return func(s *Server) {
+		s.clientID = clientID
+		s.clientSecret = clientSecret
+	}
+}
+
+// WithTokenExpiry is a server option that sets the lifetime of issued access tokens.
+// As the UAA reports the lifetime in seconds, it is rounded down to seconds (but at least one second).
+// Note that clients refresh their token cf.TokenExpirySafetyMargin before it expires. Defaults to DefaultTokenExpiry
+func WithTokenExpiry(expiry time.Duration) Option {
+	return func(s *Server) {
+		s.tokenExpiry = max(expiry.Truncate(time.Second), time.Second)
+	}
+}
+
+// WithJobPolls is a server option that sets how often a job reports PROCESSING before it is finished.
+// Defaults to 0, so jobs are finished on the first poll
+func WithJobPolls(polls int) Option {
+	return func(s *Server) {
+		s.jobPolls = max(polls, 0)
+	}
+}
+
+// WithFixtures is a server option that seeds the server with the given resources (see Server.Seed)
+func WithFixtures(fixtures Fixtures) Option {
+	return func(s *Server) {
+		if err := s.Seed(fixtures); err != nil {
+			panic(fmt.Sprintf("cftest: cannot seed fixtures: %v", err))
+		}
+	}
+}
+
+// NewServer starts a new fake Cloud Controller and UAA. The server must be closed using Close
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		clientID:      DefaultClientID,
+		tokenExpiry:   DefaultTokenExpiry,
+		accessTokens:  make(map[string]time.Time),
+		refreshTokens: make(map[string]struct{}),
+		passcodes:     make(map[string]struct{}),
+		assertions:    make(map[string]struct{}),
+		jobs:          make(map[string]*job),
+	}
+	s.mux = http.NewServeMux()
+	s.routes()
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+
+	// the options are applied after the server has been started, so fixtures can link to the server
+	for _, option := range options {
+		option(s)
+	}
+	if len(s.users) == 0 {
+		s.users = []credentials{{username: DefaultUsername, password: DefaultPassword}}
+	}
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Config returns a config which authenticates against the server using the password grant
+// with the credentials of the first user
+func (s *Server) Config() *cf.CloudFoundryConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &cf.CloudFoundryConfig{
+		APIEndpoint:       s.URL,
+		AuthEndpoint:      s.URL,
+		UAAEndpoint:       s.URL,
+		OAuthClientID:     s.clientID,
+		OAuthClientSecret: s.clientSecret,
+		Username:          s.users[0].username,
+		Password:          s.users[0].password,
+	}
+}
+
+// NewClient returns a client which is authenticated against the server using Config
+func (s *Server) NewClient(options ...cf.ClientOption) (*cf.CloudFoundryClient, error) {
+	return s.Config().NewClient(options...)
+}
+
+// serveHTTP applies matching faults before the request is handled
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.applyFault(w, r) {
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// routes registers the handlers of the UAA and the Cloud Controller
+func (s *Server) routes() {
+	s.mux.HandleFunc("POST /oauth/token", s.handleToken)
+	s.mux.HandleFunc("GET /{$}", s.handleRoot)
+	s.mux.HandleFunc("GET /v3/info", s.handleInfo)
+
+	s.mux.HandleFunc("GET /v3/organizations", s.authenticated(s.handleListOrganizations))
+	s.mux.HandleFunc("GET /v3/organizations/{guid}", s.authenticated(s.handleGetOrganization))
+
+	s.mux.HandleFunc("GET /v3/spaces", s.authenticated(s.handleListSpaces))
+	s.mux.HandleFunc("POST /v3/spaces", s.authenticated(s.handleCreateSpace))
+	s.mux.HandleFunc("GET /v3/spaces/{guid}", s.authenticated(s.handleGetSpace))
+	s.mux.HandleFunc("PATCH /v3/spaces/{guid}", s.authenticated(s.handleUpdateSpace))
+	s.mux.HandleFunc("DELETE /v3/spaces/{guid}", s.authenticated(s.handleDeleteSpace))
+	s.mux.HandleFunc("GET /v3/spaces/{guid}/users", s.authenticated(s.handleListSpaceUsers))
+
+	s.mux.HandleFunc("GET /v3/users", s.authenticated(s.handleListUsers))
+	s.mux.HandleFunc("POST /v3/users", s.authenticated(s.handleCreateUser))
+	s.mux.HandleFunc("GET /v3/users/{guid}", s.authenticated(s.handleGetUser))
+	s.mux.HandleFunc("PATCH /v3/users/{guid}", s.authenticated(s.handleUpdateUser))
+	s.mux.HandleFunc("DELETE /v3/users/{guid}", s.authenticated(s.handleDeleteUser))
+
+	s.mux.HandleFunc("GET /v3/roles", s.authenticated(s.handleListRoles))
+	s.mux.HandleFunc("POST /v3/roles", s.authenticated(s.handleCreateRole))
+	s.mux.HandleFunc("GET /v3/roles/{guid}", s.authenticated(s.handleGetRole))
+	s.mux.HandleFunc("DELETE /v3/roles/{guid}", s.authenticated(s.handleDeleteRole))
+
+	s.mux.HandleFunc("GET /v3/jobs/{guid}", s.authenticated(s.handleGetJob))
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, 10000, "CF-NotFound", "Unknown request")
+	})
+}
+
+// newGUID returns a random version 4 UUID
+func newGUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// newToken returns a random opaque token
+func newToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,323 @@
+package cftest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+const orgGUID = "3f1b2c4d-0000-4000-8000-000000000001"
+
+// newSpace returns a space fixture in the organization orgGUID
+func newSpace(name string, labels map[string]string) models.Space {
+	space := models.Space{Name: name}
+	space.Relationships.Organization.Data.Guid = orgGUID
+	space.Metadata.Labels = labels
+	return space
+}
+
+// newServer starts a server with an organization and the given number of spaces named space-1, space-2, ...
+func newServer(t *testing.T, spaces int, options ...cftest.Option) *cftest.Server {
+	t.Helper()
+	fixtures := cftest.Fixtures{
+		Organizations: []models.Organization{{Guid: orgGUID, Name: "org"}},
+	}
+	for i := 1; i <= spaces; i++ {
+		labels := map[string]string{"env": "dev"}
+		if i%2 == 0 {
+			labels["env"] = "prod"
+		}
+		fixtures.Spaces = append(fixtures.Spaces, newSpace(fmt.Sprintf("space-%d", i), labels))
+	}
+	server := cftest.NewServer(append(options, cftest.WithFixtures(fixtures))...)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newClient returns a client which is authenticated against the server
+func newClient(t *testing.T, server *cftest.Server, options ...cf.ClientOption) *cf.CloudFoundryClient {
+	t.Helper()
+	client, err := server.NewClient(options...)
+	if err != nil {
+		t.Fatalf("cannot create client: %v", err)
+	}
+	return client
+}
+
+// get sends an authenticated GET request with the raw query to the server and decodes the response into result
+func get(t *testing.T, server *cftest.Server, client *cf.CloudFoundryClient, path string, result any) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "bearer "+client.GetTokenInfo().AccessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != nil {
+		if err := json.Unmarshal(body, result); err != nil {
+			t.Fatalf("cannot decode %s: %v", body, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// spacePage is a page of spaces
+type spacePage struct {
+	Pagination cf.PaginationInfo `json:"pagination"`
+	Resources  []models.Space    `json:"resources"`
+}
+
+// names returns the names of the spaces
+func names(spaces []models.Space) []string {
+	result := make([]string, len(spaces))
+	for i, space := range spaces {
+		result[i] = space.Name
+	}
+	return result
+}
+
+func TestServerIssuesTokensForSupportedGrants(t *testing.T) {
+	server := newServer(t, 0, cftest.WithPasscode("passcode"), cftest.WithJWTAssertion("assertion"))
+	client := newClient(t, server)
+	if err := client.RefreshToken(); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	cfg := server.Config()
+	cfg.GrantType = cf.ClientCredentialsGrant
+	if _, err := cfg.NewClient(); err != nil {
+		t.Fatalf("client credentials grant failed: %v", err)
+	}
+
+	cfg = server.Config()
+	cfg.GrantType, cfg.Passcode = cf.PasscodeGrant, "passcode"
+	if _, err := cfg.NewClient(); err != nil {
+		t.Fatalf("passcode grant failed: %v", err)
+	}
+	// passcodes can only be used once
+	var tokenErr *cf.TokenError
+	if _, err := cfg.NewClient(); !errors.As(err, &tokenErr) || tokenErr.ErrorCode != "unauthorized" {
+		t.Errorf("err = %v, want the used passcode to be rejected", err)
+	}
+
+	cfg = server.Config()
+	cfg.GrantType, cfg.JWTAssertion = cf.JWTBearerGrant, "assertion"
+	if _, err := cfg.NewClient(); err != nil {
+		t.Fatalf("JWT bearer grant failed: %v", err)
+	}
+
+	want := []string{"password", "refresh_token", "client_credentials", "password", string(cf.JWTBearerGrant)}
+	if got := server.TokenGrants(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("grants = %v, want %v", got, want)
+	}
+}
+
+func TestServerRejectsBadCredentials(t *testing.T) {
+	server := newServer(t, 0)
+	cfg := server.Config()
+	cfg.Password = "wrong"
+
+	_, err := cfg.NewClient()
+	var tokenErr *cf.TokenError
+	if !errors.As(err, &tokenErr) || tokenErr.ErrorCode != "unauthorized" {
+		t.Fatalf("err = %v, want unauthorized token error", err)
+	}
+}
+
+func TestServerRejectsExpiredAndRevokedTokens(t *testing.T) {
+	server := newServer(t, 0)
+	client := newClient(t, server)
+
+	server.ExpireTokens()
+	var errs struct {
+		Errors []cf.CloudFoundryError `json:"errors"`
+	}
+	if status := get(t, server, client, "/v3/spaces", &errs); status != http.StatusUnauthorized ||
+		len(errs.Errors) != 1 || errs.Errors[0].Code != cf.InvalidAuthTokenErrorCode {
+		t.Fatalf("status = %d, errors = %v, want CF-InvalidAuthToken", status, errs.Errors)
+	}
+
+	// the client refreshes the expired token on its own
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("list after expiry failed: %v", err)
+	}
+
+	// revoked refresh tokens force the client to use the password grant again
+	server.RevokeTokens()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("list after revocation failed: %v", err)
+	}
+	grants := server.TokenGrants()
+	if grants[len(grants)-1] != "password" {
+		t.Errorf("grants = %v, want a final password grant", grants)
+	}
+}
+
+func TestServerPaginates(t *testing.T) {
+	server := newServer(t, 5)
+	client := newClient(t, server)
+
+	var page spacePage
+	get(t, server, client, "/v3/spaces?per_page=2&page=2", &page)
+	if got := names(page.Resources); fmt.Sprint(got) != "[space-3 space-4]" {
+		t.Errorf("resources = %v, want [space-3 space-4]", got)
+	}
+	if page.Pagination.TotalResults != 5 || page.Pagination.TotalPages != 3 {
+		t.Errorf("pagination = %+v, want 5 results on 3 pages", page.Pagination)
+	}
+	if page.Pagination.Next == nil || page.Pagination.Previous == nil {
+		t.Fatalf("pagination = %+v, want next and previous links", page.Pagination)
+	}
+
+	var next spacePage
+	get(t, server, client, page.Pagination.Next.Href[len(server.URL):], &next)
+	if got := names(next.Resources); fmt.Sprint(got) != "[space-5]" || next.Pagination.Next != nil {
+		t.Errorf("next page = %v (next %v), want the last page [space-5]", got, next.Pagination.Next)
+	}
+}
+
+func TestServerUsesLastValueOfRepeatedParameters(t *testing.T) {
+	server := newServer(t, 5)
+	client := newClient(t, server)
+
+	var page spacePage
+	get(t, server, client, "/v3/spaces?page=1&per_page=1&page=3&per_page=2&names=space-1&names=space-5,space-6", &page)
+	if got := names(page.Resources); fmt.Sprint(got) != "[]" || page.Pagination.TotalResults != 1 {
+		t.Errorf("resources = %v of %d, want page 3 of the space-5 result", got, page.Pagination.TotalResults)
+	}
+
+	get(t, server, client, "/v3/spaces?order_by=name&order_by=-name&per_page=1", &page)
+	if got := names(page.Resources); fmt.Sprint(got) != "[space-5]" {
+		t.Errorf("resources = %v, want [space-5] ordered by -name", got)
+	}
+}
+
+func TestServerFiltersSpaces(t *testing.T) {
+	server := newServer(t, 4)
+	client := newClient(t, server)
+
+	tests := []struct {
+		name    string
+		options cf.ListSpacesOptions
+		want    string
+	}{
+		{"names", cf.ListSpacesOptions{Names: []string{"space-1", "space-3"}}, "[space-1 space-3]"},
+		{"label selector", cf.ListSpacesOptions{LabelSelector: "env=prod"}, "[space-2 space-4]"},
+		{"organization", cf.ListSpacesOptions{OrganizationGUIDs: []string{"other"}}, "[]"},
+		{"order", cf.ListSpacesOptions{OrderBy: cf.OrderByNameDesc}, "[space-4 space-3 space-2 space-1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spaces, err := client.ListSpaces(tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(names(spaces)); got != tt.want {
+				t.Errorf("spaces = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerRejectsInvalidQueries(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server)
+
+	for _, query := range []string{"unknown=1", "per_page=0", "page=0", "order_by=size", "include=user", "label_selector=!!"} {
+		t.Run(query, func(t *testing.T) {
+			var errs struct {
+				Errors []cf.CloudFoundryError `json:"errors"`
+			}
+			status := get(t, server, client, "/v3/spaces?"+query, &errs)
+			if status != http.StatusBadRequest || len(errs.Errors) != 1 || errs.Errors[0].Title != "CF-BadQueryParameter" {
+				t.Errorf("status = %d, errors = %v, want CF-BadQueryParameter", status, errs.Errors)
+			}
+		})
+	}
+}
+
+func TestServerIncludesOrganizations(t *testing.T) {
+	server := newServer(t, 2)
+	client := newClient(t, server)
+
+	spaces, err := client.ListSpacesEnriched(cf.ListSpacesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, space := range spaces {
+		if space.Organization == nil || space.Organization.Guid != orgGUID {
+			t.Errorf("space %s has organization %v, want %s", space.Name, space.Organization, orgGUID)
+		}
+	}
+}
+
+func TestServerRunsJobs(t *testing.T) {
+	server := newServer(t, 0, cftest.WithJobPolls(2))
+	client := newClient(t, server, cf.WithJobPollPolicy(cf.JobPollPolicy{InitialInterval: 1, MaxInterval: 1}))
+	user, err := client.CreateUser("user-guid", cf.CreateUserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, err := client.DeleteUser(user.Guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := handle.Wait()
+	if err != nil || job.State != models.JobStateComplete {
+		t.Fatalf("job = %v, err = %v, want a completed job", job, err)
+	}
+	if len(server.Users()) != 0 {
+		t.Errorf("users = %v, want the user to be deleted", server.Users())
+	}
+
+	server.FailNextJob(models.JobError{Code: 10008, Title: "CF-UnprocessableEntity", Detail: "failed"})
+	user, _ = client.CreateUser("other-guid", cf.CreateUserOptions{})
+	handle, err = client.DeleteUser(user.Guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jobErr *cf.JobFailedError
+	if _, err := handle.Wait(); !errors.As(err, &jobErr) {
+		t.Errorf("err = %v, want a JobFailedError", err)
+	}
+}
+
+func TestServerInjectsFaults(t *testing.T) {
+	server := newServer(t, 1)
+	client := newClient(t, server, cf.WithRetryPolicy(cf.RetryPolicy{MaxAttempts: 1}))
+
+	server.InjectFault(cftest.Fault{Method: http.MethodGet, Path: "/v3/spaces", Status: http.StatusServiceUnavailable, Times: 1})
+	var apiErr *cf.APIError
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want a 503 API error", err)
+	}
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("fault was applied more than once: %v", err)
+	}
+
+	server.InjectFault(cftest.Fault{Path: "/v3/spaces", Abort: true})
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want a transport error", err)
+	}
+	server.ClearFaults()
+	if _, err := client.ListSpaces(cf.ListSpacesOptions{}); err != nil {
+		t.Fatalf("faults were not cleared: %v", err)
+	}
+}
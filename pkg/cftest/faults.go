@@ -0,0 +1,135 @@
+package cftest
+
+import (
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Fault is an injected failure of the requests matching its method and path.
+// A fault can delay requests, replace their response or abort their connection
+type Fault struct {
+	// Method is the HTTP method of the matching requests. Empty matches all methods
+	Method string
+
+	// Path is a pattern (see path.Match) of the paths of the matching requests, e.g. "/v3/spaces/*".
+	// Empty matches all paths including the token endpoint /oauth/token
+	Path string
+
+	// Delay delays the matching requests before they are handled (or failed)
+	Delay time.Duration
+
+	// Status is the status code of the injected response.
+	// If 0, the request is handled normally after the Delay, unless Abort is set
+	Status int
+
+	// Errors are the errors of the injected CF-style error response.
+	// Defaults to a single CF-UnknownError if Status is an error status
+	Errors []cf.CloudFoundryError
+
+	// Body is the raw body of the injected response. It takes precedence over Errors,
+	// e.g. to inject a UAA error response
+	Body []byte
+
+	// Header contains additional headers of the injected response, e.g. Retry-After or X-RateLimit-Remaining
+	Header http.Header
+
+	// Abort closes the connection without a complete response, which causes a transport error in the client
+	Abort bool
+
+	// Times is the number of requests the fault is applied to. If 0, it is applied to all matching requests
+	Times int
+
+	applied int
+}
+
+// InjectFault adds a fault which is applied to all matching requests.
+// Faults are matched in the order they were injected, at most one fault is applied per request
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// matches returns true if the fault applies to the request
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Method != "" && f.Method != r.Method {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+			return false
+		}
+	}
+	return f.Times == 0 || f.applied < f.Times
+}
+
+// applyFault applies the first fault matching the request.
+// It returns true if the response has been replaced by the fault
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	var fault *Fault
+	for _, f := range s.faults {
+		if f.matches(r) {
+			f.applied++
+			copied := *f
+			fault = &copied
+			break
+		}
+	}
+	s.mu.Unlock()
+	if fault == nil {
+		return false
+	}
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if fault.Abort {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				// a truncated response is written, as net/http transparently retries idempotent requests
+				// on reused connections which are closed before anything has been received.
+				// The client fails to read the body with io.ErrUnexpectedEOF
+				_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 64\r\n\r\n{"))
+				_ = conn.Close()
+				return true
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if fault.Status == 0 {
+		return false
+	}
+
+	for key, values := range fault.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	switch {
+	case fault.Body != nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fault.Status)
+		_, _ = w.Write(fault.Body)
+	case len(fault.Errors) > 0:
+		writeErrors(w, fault.Status, fault.Errors...)
+	case fault.Status >= 400:
+		writeError(w, fault.Status, 10001, "UnknownError", "An unknown error occurred.")
+	default:
+		w.WriteHeader(fault.Status)
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package cftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// Fixtures are resources which are added to a Server.
+// Missing GUIDs, timestamps and links are filled in when the fixtures are seeded
+type Fixtures struct {
+	Organizations []models.Organization `json:"organizations"`
+	Spaces        []models.Space        `json:"spaces"`
+	Users         []models.User         `json:"users"`
+	Roles         []models.Role         `json:"roles"`
+}
+
+// LoadFixtures reads fixtures from the JSON file at the given path. The resources use the same format
+// as the responses of the Cloud Controller, e.g.
+//
+//	{"organizations": [{"guid": "org-guid", "name": "my-org"}]}
+func LoadFixtures(path string) (Fixtures, error) {
+	var fixtures Fixtures
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("cannot parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// Seed adds the resources of the fixtures to the server. Relationships may refer to resources
+// which have already been added to the server or which are part of the fixtures.
+// An error is returned if a relationship refers to a missing resource or a resource is not unique
+func (s *Server) Seed(fixtures Fixtures) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, org := range fixtures.Organizations {
+		if _, err := s.addOrganizationLocked(org); err != nil {
+			return err
+		}
+	}
+	for _, space := range fixtures.Spaces {
+		if _, err := s.addSpaceLocked(space); err != nil {
+			return err
+		}
+	}
+	for _, user := range fixtures.Users {
+		if _, err := s.addUserLocked(user); err != nil {
+			return err
+		}
+	}
+	for _, role := range fixtures.Roles {
+		if _, err := s.addRoleLocked(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Organizations returns a copy of all organizations of the server
+func (s *Server) Organizations() []models.Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.Organization(nil), s.organizations...)
+}
+
+// Spaces returns a copy of all spaces of the server
+func (s *Server) Spaces() []models.Space {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.Space(nil), s.spaces...)
+}
+
+// Users returns a copy of all users of the server
+func (s *Server) Users() []models.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.User(nil), s.ccUsers...)
+}
+
+// Roles returns a copy of all roles of the server
+func (s *Server) Roles() []models.Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.Role(nil), s.roles...)
+}
+
+// conflictError is returned if a resource cannot be added because of a missing relationship
+// or because it is not unique
+type conflictError struct {
+	code   int
+	title  string
+	detail string
+}
+
+// Error returns the detail of the error
+func (e *conflictError) Error() string {
+	return e.detail
+}
+
+// unprocessable returns a CF-UnprocessableEntity error
+func unprocessable(format string, args ...any) error {
+	return &conflictError{code: 10008, title: "CF-UnprocessableEntity", detail: fmt.Sprintf(format, args...)}
+}
+
+// notUnique returns a CF-UniquenessError
+func notUnique(format string, args ...any) error {
+	return &conflictError{code: 10016, title: "CF-UniquenessError", detail: fmt.Sprintf(format, args...)}
+}
+
+// stamp fills in a missing GUID and missing timestamps
+func stamp(guid *string, createdAt, updatedAt *time.Time) {
+	if *guid == "" {
+		*guid = newGUID()
+	}
+	now := time.Now().UTC()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = *createdAt
+	}
+}
+
+// normalizeMetadata makes sure the maps of the metadata are not nil, as the Cloud Controller returns empty objects
+func normalizeMetadata(metadata *models.Metadata) {
+	if metadata.Labels == nil {
+		metadata.Labels = make(map[string]string)
+	}
+	if metadata.Annotations == nil {
+		metadata.Annotations = make(map[string]string)
+	}
+}
+
+// addOrganizationLocked adds the organization and returns the added organization. s.mu must be held by the caller
+func (s *Server) addOrganizationLocked(org models.Organization) (models.Organization, error) {
+	stamp(&org.Guid, &org.CreatedAt, &org.UpdatedAt)
+	normalizeMetadata(&org.Metadata)
+	if s.organization(org.Guid) != nil {
+		return org, notUnique("Organization GUID '%s' is taken.", org.Guid)
+	}
+	for _, other := range s.organizations {
+		if strings.EqualFold(other.Name, org.Name) {
+			return org, notUnique("Organization '%s' already exists.", org.Name)
+		}
+	}
+	self := s.URL + "/v3/organizations/" + org.Guid
+	org.Links.Self.Href = self
+	org.Links.Domains.Href = self + "/domains"
+	org.Links.DefaultDomain.Href = self + "/domains/default"
+	if quota := org.Relationships.Quota.Data.Guid; quota != "" {
+		org.Links.Quota.Href = s.URL + "/v3/organization_quotas/" + quota
+	}
+	s.organizations = append(s.organizations, org)
+	return org, nil
+}
+
+// addSpaceLocked adds the space and returns the added space. s.mu must be held by the caller
+func (s *Server) addSpaceLocked(space models.Space) (models.Space, error) {
+	stamp(&space.Guid, &space.CreatedAt, &space.UpdatedAt)
+	normalizeMetadata(&space.Metadata)
+	orgGUID := space.Relationships.Organization.Data.Guid
+	if s.organization(orgGUID) == nil {
+		return space, unprocessable("Invalid organization. Ensure the organization exists and you have access to it.")
+	}
+	if s.space(space.Guid) != nil {
+		return space, notUnique("Space GUID '%s' is taken.", space.Guid)
+	}
+	for _, other := range s.spaces {
+		if other.Relationships.Organization.Data.Guid == orgGUID && strings.EqualFold(other.Name, space.Name) {
+			return space, notUnique("Name must be unique per organization")
+		}
+	}
+	self := s.URL + "/v3/spaces/" + space.Guid
+	space.Links.Self.Href = self
+	space.Links.Features.Href = self + "/features"
+	space.Links.Organization.Href = s.URL + "/v3/organizations/" + orgGUID
+	space.Links.ApplyManifest.Href = self + "/actions/apply_manifest"
+	space.Links.ApplyManifest.Method = "POST"
+	s.spaces = append(s.spaces, space)
+	return space, nil
+}
+
+// addUserLocked adds the user and returns the added user. s.mu must be held by the caller
+func (s *Server) addUserLocked(user models.User) (models.User, error) {
+	stamp(&user.Guid, &user.CreatedAt, &user.UpdatedAt)
+	normalizeMetadata(&user.Metadata)
+	if s.user(user.Guid) != nil {
+		return user, notUnique("User with guid '%s' already exists.", user.Guid)
+	}
+	if user.Username != "" && user.Origin == "" {
+		user.Origin = "uaa"
+	}
+	if user.PresentationName == "" {
+		user.PresentationName = user.Username
+	}
+	if user.PresentationName == "" {
+		user.PresentationName = user.Guid
+	}
+	user.Links.Self.Href = s.URL + "/v3/users/" + user.Guid
+	s.ccUsers = append(s.ccUsers, user)
+	return user, nil
+}
+
+// addRoleLocked adds the role and returns the added role. s.mu must be held by the caller
+func (s *Server) addRoleLocked(role models.Role) (models.Role, error) {
+	stamp(&role.Guid, &role.CreatedAt, &role.UpdatedAt)
+	user := s.user(role.GetUserID())
+	if user == nil {
+		return role, unprocessable("Invalid user. Ensure that the user exists and you have access to it.")
+	}
+	orgGUID, isOrgRole := role.GetOrganizationID()
+	spaceGUID, isSpaceRole := role.GetSpaceID()
+	switch {
+	case strings.HasPrefix(role.Type, "organization_") && isOrgRole && !isSpaceRole:
+		if s.organization(orgGUID) == nil {
+			return role, unprocessable("Invalid organization. Ensure that the organization exists and you have access to it.")
+		}
+	case strings.HasPrefix(role.Type, "space_") && isSpaceRole && !isOrgRole:
+		if s.space(spaceGUID) == nil {
+			return role, unprocessable("Invalid space. Ensure that the space exists and you have access to it.")
+		}
+	default:
+		return role, unprocessable("Role type '%s' is invalid for the given relationships.", role.Type)
+	}
+	if s.role(role.Guid) != nil {
+		return role, notUnique("Role with guid '%s' already exists.", role.Guid)
+	}
+	for _, other := range s.roles {
+		if other.Type == role.Type && other.GetUserID() == role.GetUserID() &&
+			other.Relationships.Organization.Data.Guid == orgGUID && other.Relationships.Space.Data.Guid == spaceGUID {
+			return role, unprocessable("User '%s' already has '%s' role.", user.PresentationName, role.Type)
+		}
+	}
+	role.Links.Self.Href = s.URL + "/v3/roles/" + role.Guid
+	role.Links.User.Href = s.URL + "/v3/users/" + role.GetUserID()
+	if isOrgRole {
+		role.Links.Organization.Href = s.URL + "/v3/organizations/" + orgGUID
+	}
+	s.roles = append(s.roles, role)
+	return role, nil
+}
@@ -0,0 +1,79 @@
+package cftest
+
+import (
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"time"
+)
+
+// job is an asynchronous operation of the fake Cloud Controller
+type job struct {
+	job models.Job
+
+	// polls is the number of polls left until the job is finished
+	polls int
+
+	// failure is the error the job fails with or nil if it succeeds
+	failure *models.JobError
+
+	// run performs the operation of the job once it is finished. s.mu is held while run is called
+	run func()
+}
+
+// FailNextJob makes the next job fail with the given error instead of performing its operation.
+// Multiple calls queue failures for the following jobs
+func (s *Server) FailNextJob(err models.JobError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobFailures = append(s.jobFailures, err)
+}
+
+// startJobLocked creates a job which performs run once it is finished and writes a 202 response
+// with the location of the job. s.mu must be held by the caller
+func (s *Server) startJobLocked(w http.ResponseWriter, operation string, run func()) {
+	j := &job{polls: s.jobPolls, run: run}
+	if len(s.jobFailures) > 0 {
+		j.failure = &s.jobFailures[0]
+		s.jobFailures = s.jobFailures[1:]
+	}
+	now := time.Now().UTC()
+	j.job = models.Job{
+		Guid:      newGUID(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Operation: operation,
+		State:     models.JobStateProcessing,
+		Errors:    []models.JobError{},
+		Warnings:  []models.JobWarning{},
+	}
+	j.job.Links.Self.Href = s.URL + "/v3/jobs/" + j.job.Guid
+	s.jobs[j.job.Guid] = j
+
+	w.Header().Set("Location", j.job.Links.Self.Href)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleGetJob returns a job. Every poll advances the job until it is finished
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[r.PathValue("guid")]
+	if !ok {
+		writeNotFound(w, "Job")
+		return
+	}
+	if !j.job.IsFinished() {
+		if j.polls > 0 {
+			j.polls--
+		} else if j.failure != nil {
+			j.job.State = models.JobStateFailed
+			j.job.Errors = append(j.job.Errors, *j.failure)
+			j.job.UpdatedAt = time.Now().UTC()
+		} else {
+			j.run()
+			j.job.State = models.JobStateComplete
+			j.job.UpdatedAt = time.Now().UTC()
+		}
+	}
+	writeJSON(w, http.StatusOK, j.job)
+}
@@ -0,0 +1,376 @@
+package cftest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/labels"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//goland:noinspection GoUnusedConst
+const (
+	// DefaultPerPage is the page size if the per_page query parameter is missing
+	DefaultPerPage = 50
+	// MaxPerPage is the maximum page size accepted by the Cloud Controller
+	MaxPerPage = 5000
+)
+
+// queryError is an invalid query parameter, which is returned as CF-BadQueryParameter
+type queryError string
+
+// Error returns the detail of the error
+func (e queryError) Error() string {
+	return string(e)
+}
+
+// meta contains the fields of a resource which are common to all resource types
+type meta struct {
+	guid      string
+	name      string
+	createdAt time.Time
+	updatedAt time.Time
+	labels    map[string]string
+}
+
+// filter is a query parameter which filters listed resources by one of their fields
+type filter[T any] func(resource T, values []string) bool
+
+// kind describes how resources of one type are listed
+type kind[T any] struct {
+	// meta returns the common fields of a resource
+	meta func(T) meta
+
+	// named is true if the resources can be filtered and ordered by name
+	named bool
+
+	// filters are the supported filter query parameters
+	filters map[string]filter[T]
+
+	// includes are the supported values of the include query parameter
+	includes []string
+
+	// include returns the included related resources of the resources. s.mu is held by the caller
+	include func(resources []T, includes []string) models.Included
+}
+
+// field returns a filter which matches resources whose field equals one of the values
+func field[T any](value func(T) string) filter[T] {
+	return func(resource T, values []string) bool {
+		return slices.Contains(values, value(resource))
+	}
+}
+
+// page is a paginated response of the Cloud Controller
+type page[T any] struct {
+	Pagination pagination       `json:"pagination"`
+	Resources  []T              `json:"resources"`
+	Included   *models.Included `json:"included,omitempty"`
+}
+
+// pagination is the pagination information of a paginated response
+type pagination struct {
+	TotalResults int   `json:"total_results"`
+	TotalPages   int   `json:"total_pages"`
+	First        *link `json:"first"`
+	Last         *link `json:"last"`
+	Next         *link `json:"next"`
+	Previous     *link `json:"previous"`
+}
+
+// link is a link to another page
+type link struct {
+	Href string `json:"href"`
+}
+
+// writeList filters, orders and paginates the resources according to the query of the request
+// and writes the requested page. s.mu must be held by the caller
+func writeList[T any](s *Server, w http.ResponseWriter, r *http.Request, k kind[T], resources []T) {
+	result, err := list(s, r, k, resources)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 10005, "CF-BadQueryParameter", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// list returns the page of resources requested by the query of the request
+func list[T any](s *Server, r *http.Request, k kind[T], resources []T) (*page[T], error) {
+	query := r.URL.Query()
+	if err := k.validateParams(query); err != nil {
+		return nil, err
+	}
+
+	perPage, err := intParam(query, "per_page", DefaultPerPage)
+	if err != nil || perPage < 1 || perPage > MaxPerPage {
+		return nil, queryError(fmt.Sprintf("Per page must be between 1 and %d", MaxPerPage))
+	}
+	pageNumber, err := intParam(query, "page", 1)
+	if err != nil || pageNumber < 1 {
+		return nil, queryError("Page must be greater than 0")
+	}
+	includes, err := k.parseIncludes(query)
+	if err != nil {
+		return nil, err
+	}
+
+	matching, err := k.filter(query, resources)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.order(lastValue(query, "order_by"), matching); err != nil {
+		return nil, err
+	}
+
+	totalPages := max((len(matching)+perPage-1)/perPage, 1)
+	start := min((pageNumber-1)*perPage, len(matching))
+	end := min(start+perPage, len(matching))
+
+	href := func(number int) *link {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(number))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return &link{Href: s.URL + r.URL.Path + "?" + q.Encode()}
+	}
+	result := &page[T]{
+		Pagination: pagination{
+			TotalResults: len(matching),
+			TotalPages:   totalPages,
+			First:        href(1),
+			Last:         href(totalPages),
+		},
+		Resources: append(make([]T, 0, end-start), matching[start:end]...),
+	}
+	if pageNumber < totalPages {
+		result.Pagination.Next = href(pageNumber + 1)
+	}
+	if pageNumber > 1 {
+		result.Pagination.Previous = href(min(pageNumber-1, totalPages))
+	}
+	if len(includes) > 0 {
+		included := k.include(result.Resources, includes)
+		result.Included = &included
+	}
+	return result, nil
+}
+
+// validateParams returns an error if the query contains parameters which are not supported by the resource type
+func (k kind[T]) validateParams(query url.Values) error {
+	var unknown []string
+	for param := range query {
+		name, _, _ := strings.Cut(param, "[")
+		switch name {
+		case "page", "per_page", "order_by", "label_selector", "created_ats", "updated_ats":
+			continue
+		case "include":
+			if len(k.includes) > 0 {
+				continue
+			}
+		case "names":
+			if k.named {
+				continue
+			}
+		default:
+			if _, ok := k.filters[name]; ok {
+				continue
+			}
+		}
+		unknown = append(unknown, "'"+param+"'")
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return queryError("Unknown query parameter(s): " + strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// parseIncludes returns the requested includes or an error if one of them is not supported
+func (k kind[T]) parseIncludes(query url.Values) ([]string, error) {
+	if !query.Has("include") {
+		return nil, nil
+	}
+	includes := splitValues(lastValue(query, "include"))
+	for _, include := range includes {
+		if !slices.Contains(k.includes, include) {
+			return nil, queryError(fmt.Sprintf("Invalid included resource: '%s'. Valid included resources are: '%s'",
+				include, strings.Join(k.includes, "', '")))
+		}
+	}
+	return includes, nil
+}
+
+// filter returns the resources matching all filters of the query
+func (k kind[T]) filter(query url.Values, resources []T) ([]T, error) {
+	matchers := make([]func(T) bool, 0, len(query))
+	for param, filter := range k.filters {
+		if query.Has(param) {
+			values := splitValues(lastValue(query, param))
+			matchers = append(matchers, func(resource T) bool {
+				return filter(resource, values)
+			})
+		}
+	}
+	if k.named && query.Has("names") {
+		names := splitValues(lastValue(query, "names"))
+		matchers = append(matchers, func(resource T) bool {
+			return slices.Contains(names, k.meta(resource).name)
+		})
+	}
+	if query.Has("label_selector") {
+		selector, err := labels.Parse(lastValue(query, "label_selector"))
+		if err != nil {
+			return nil, queryError(err.Error())
+		}
+		matchers = append(matchers, func(resource T) bool {
+			return selector.Matches(k.meta(resource).labels)
+		})
+	}
+	for param := range query {
+		name, operator, ok := strings.Cut(param, "[")
+		if name != "created_ats" && name != "updated_ats" {
+			continue
+		}
+		operator = strings.TrimSuffix(operator, "]")
+		if !ok {
+			operator = ""
+		}
+		matcher, err := timestampMatcher(operator, lastValue(query, param))
+		if err != nil {
+			return nil, queryError(fmt.Sprintf("Invalid %s: %v", param, err))
+		}
+		created := name == "created_ats"
+		matchers = append(matchers, func(resource T) bool {
+			m := k.meta(resource)
+			if created {
+				return matcher(m.createdAt)
+			}
+			return matcher(m.updatedAt)
+		})
+	}
+
+	var result []T
+	for _, resource := range resources {
+		if !slices.ContainsFunc(matchers, func(match func(T) bool) bool { return !match(resource) }) {
+			result = append(result, resource)
+		}
+	}
+	return result, nil
+}
+
+// timestampMatcher returns a function which compares a timestamp using the operator
+// to the RFC3339 timestamps of the value
+func timestampMatcher(operator, value string) (func(time.Time) bool, error) {
+	var timestamps []time.Time
+	for _, v := range splitValues(value) {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+		timestamps = append(timestamps, t.Truncate(time.Second))
+	}
+	if len(timestamps) == 0 {
+		return nil, fmt.Errorf("missing timestamp")
+	}
+	if operator != "" && len(timestamps) > 1 {
+		return nil, fmt.Errorf("only one timestamp is allowed for the %s operator", operator)
+	}
+	switch operator {
+	case "":
+		return func(t time.Time) bool {
+			return slices.ContainsFunc(timestamps, t.Truncate(time.Second).Equal)
+		}, nil
+	case "lt":
+		return func(t time.Time) bool { return t.Truncate(time.Second).Before(timestamps[0]) }, nil
+	case "lte":
+		return func(t time.Time) bool { return !t.Truncate(time.Second).After(timestamps[0]) }, nil
+	case "gt":
+		return func(t time.Time) bool { return t.Truncate(time.Second).After(timestamps[0]) }, nil
+	case "gte":
+		return func(t time.Time) bool { return !t.Truncate(time.Second).Before(timestamps[0]) }, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", operator)
+}
+
+// order sorts the resources according to the order_by query parameter. Defaults to the creation time
+func (k kind[T]) order(orderBy string, resources []T) error {
+	attribute := strings.TrimPrefix(orderBy, "-")
+	var compare func(a, b meta) int
+	switch {
+	case attribute == "" || attribute == "created_at":
+		compare = func(a, b meta) int { return a.createdAt.Compare(b.createdAt) }
+	case attribute == "updated_at":
+		compare = func(a, b meta) int { return a.updatedAt.Compare(b.updatedAt) }
+	case attribute == "name" && k.named:
+		compare = func(a, b meta) int { return cmp.Compare(a.name, b.name) }
+	case k.named:
+		return queryError("Order by can only be: 'created_at', 'updated_at', 'name'")
+	default:
+		return queryError("Order by can only be: 'created_at', 'updated_at'")
+	}
+	descending := strings.HasPrefix(orderBy, "-")
+	slices.SortStableFunc(resources, func(a, b T) int {
+		if descending {
+			return compare(k.meta(b), k.meta(a))
+		}
+		return compare(k.meta(a), k.meta(b))
+	})
+	return nil
+}
+
+// writeResource writes a single resource including the requested related resources. s.mu must be held by the caller
+func writeResource[T any](w http.ResponseWriter, r *http.Request, k kind[T], resource T) {
+	includes, err := k.parseIncludes(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 10005, "CF-BadQueryParameter", err.Error())
+		return
+	}
+	if len(includes) == 0 {
+		writeJSON(w, http.StatusOK, resource)
+		return
+	}
+	// the included resources are returned in the same object as the resource
+	data, err := json.Marshal(resource)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, 10001, "UnknownError", err.Error())
+		return
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		writeError(w, http.StatusInternalServerError, 10001, "UnknownError", err.Error())
+		return
+	}
+	fields["included"] = k.include([]T{resource}, includes)
+	writeJSON(w, http.StatusOK, fields)
+}
+
+// intParam returns the integer query parameter or the fallback if it is missing
+func intParam(query url.Values, name string, fallback int) (int, error) {
+	if !query.Has(name) {
+		return fallback, nil
+	}
+	return strconv.Atoi(lastValue(query, name))
+}
+
+// lastValue returns the last value of the query parameter or an empty string if it is missing.
+// Like the Cloud Controller, the last value of a repeated parameter wins
+func lastValue(query url.Values, name string) string {
+	values := query[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// splitValues splits a comma-separated query parameter
+func splitValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
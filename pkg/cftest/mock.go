@@ -0,0 +1,604 @@
+package cftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"github.com/go-resty/resty/v2"
+	"slices"
+	"sync"
+)
+
+var (
+	// UnexpectedCallErr is returned by a Mock if a method is called which has no programmed response
+	UnexpectedCallErr = errors.New("unexpected call")
+)
+
+// Call is a call of a Mock method
+type Call struct {
+	// Method is the name of the called method without the WithContext suffix, e.g. "GetSpace"
+	Method string
+
+	// Args are the arguments of the call without the context
+	Args []any
+}
+
+// Mock is an implementation of cf.CloudFoundryAPI with programmable responses which records all calls.
+// The response of a method is programmed by setting its function, which is used for both the plain and the
+// WithContext variant of the method. Methods without a function return UnexpectedCallErr.
+// Job handles pointing to the mock itself can be returned using cf.NewJobHandle:
+//
+//	mock := &cftest.Mock{
+//		GetSpaceFunc: func(ctx context.Context, guid string) (*models.Space, error) {
+//			return &models.Space{Guid: guid, Name: "dev"}, nil
+//		},
+//	}
+//	mock.DeleteUserFunc = func(ctx context.Context, userGUID string) (*cf.JobHandle, error) {
+//		return cf.NewJobHandle(mock, "job-guid", ""), nil
+//	}
+//
+// A Mock is safe for concurrent use, but its functions must not be changed while it is in use
+type Mock struct {
+	// Requester
+	SendRequestFunc func(
+		ctx context.Context,
+		method string,
+		path any,
+		modifiers ...cf.RequestModifier,
+	) (*resty.Response, error)
+
+	// InfoAPI
+	GetRootFunc func(ctx context.Context) (*models.Root, error)
+	GetInfoFunc func(ctx context.Context) (*models.Info, error)
+
+	// JobsAPI
+	GetJobFunc     func(ctx context.Context, jobGUID string) (*models.Job, error)
+	WaitForJobFunc func(ctx context.Context, jobGUID string) (*models.Job, error)
+
+	// OrganizationsAPI
+	ListOrganizationsFunc         func(ctx context.Context, options cf.ListOrganizationsOptions) ([]models.Organization, error)
+	ListOrganizationsIteratorFunc func(options cf.ListOrganizationsOptions) *cf.PageIterator[models.Organization]
+
+	// SpacesAPI
+	ListSpacesFunc                func(ctx context.Context, options cf.ListSpacesOptions) ([]models.Space, error)
+	ListSpacesIteratorFunc        func(options cf.ListSpacesOptions) *cf.PageIterator[models.Space]
+	ListSpacesEnrichedFunc        func(ctx context.Context, options cf.ListSpacesOptions) ([]models.EnrichedSpace, error)
+	GetSpaceFunc                  func(ctx context.Context, guid string) (*models.Space, error)
+	GetSpaceEnrichedFunc          func(ctx context.Context, guid string) (*models.EnrichedSpace, error)
+	CreateSpaceFunc               func(ctx context.Context, name, orgGUID string, options cf.CreateSpaceOptions) (*models.Space, error)
+	UpdateSpaceFunc               func(ctx context.Context, guid string, options cf.UpdateSpaceOptions) (*models.Space, error)
+	ListUsersForSpaceFunc         func(ctx context.Context, spaceGUID string, options cf.ListUsersForSpaceOptions) ([]models.User, error)
+	ListUsersForSpaceIteratorFunc func(spaceGUID string, options cf.ListUsersForSpaceOptions) *cf.PageIterator[models.User]
+
+	// UsersAPI
+	ListUsersFunc         func(ctx context.Context, options cf.ListUsersOptions) ([]models.User, error)
+	ListUsersIteratorFunc func(options cf.ListUsersOptions) *cf.PageIterator[models.User]
+	GetUserFunc           func(ctx context.Context, userGUID string) (*models.User, error)
+	CreateUserFunc        func(ctx context.Context, guid string, options cf.CreateUserOptions) (*models.User, error)
+	UpdateUserFunc        func(ctx context.Context, guid string, metadata *models.MetadataPatch) (*models.User, error)
+	DeleteUserFunc        func(ctx context.Context, userGUID string) (*cf.JobHandle, error)
+
+	// RolesAPI
+	ListRoleFunc         func(ctx context.Context, options cf.ListRoleOptions) ([]models.Role, error)
+	ListRoleIteratorFunc func(options cf.ListRoleOptions) *cf.PageIterator[models.Role]
+	ListRoleEnrichedFunc func(ctx context.Context, options cf.ListRoleOptions) ([]models.EnrichedRole, error)
+	GetRoleFunc          func(ctx context.Context, roleGUID string) (*models.Role, error)
+	GetRoleEnrichedFunc  func(ctx context.Context, roleGUID string, include ...cf.Include) (*models.EnrichedRole, error)
+	CreateRoleFunc       func(ctx context.Context, role cf.Role, spaceOrOrganizationGUID string, options cf.CreateRoleOptions) (*models.Role, error)
+	DeleteRoleFunc       func(ctx context.Context, roleGUID string) (*cf.JobHandle, error)
+
+	// mu guards calls
+	mu    sync.Mutex
+	calls []Call
+}
+
+// make sure the mock implements all interfaces of the client
+var _ cf.CloudFoundryAPI = (*Mock)(nil)
+
+// Calls returns all recorded calls in the order they were made
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.calls)
+}
+
+// CallsTo returns the recorded calls of the given method, e.g. "GetSpace" (for GetSpace and GetSpaceWithContext)
+func (m *Mock) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []Call
+	for _, call := range m.calls {
+		if call.Method == method {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// Reset removes all recorded calls. The programmed responses are kept
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+}
+
+// record records a call of the given method
+func (m *Mock) record(method string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// unexpectedCall returns the error for a call of a method without programmed response
+func unexpectedCall(method string) error {
+	return fmt.Errorf("%w: %s has no programmed response", UnexpectedCallErr, method)
+}
+
+// SendRequest records the call and returns the response of SendRequestFunc
+func (m *Mock) SendRequest(method string, path any, modifiers ...cf.RequestModifier) (*resty.Response, error) {
+	return m.SendRequestWithContext(context.Background(), method, path, modifiers...)
+}
+
+// SendRequestWithContext is like SendRequest but passes the context to SendRequestFunc
+func (m *Mock) SendRequestWithContext(
+	ctx context.Context,
+	method string,
+	path any,
+	modifiers ...cf.RequestModifier,
+) (*resty.Response, error) {
+	m.record("SendRequest", method, path, modifiers)
+	if m.SendRequestFunc == nil {
+		return nil, unexpectedCall("SendRequest")
+	}
+	return m.SendRequestFunc(ctx, method, path, modifiers...)
+}
+
+// GetRoot records the call and returns the response of GetRootFunc
+func (m *Mock) GetRoot() (*models.Root, error) {
+	return m.GetRootWithContext(context.Background())
+}
+
+// GetRootWithContext is like GetRoot but passes the context to GetRootFunc
+func (m *Mock) GetRootWithContext(ctx context.Context) (*models.Root, error) {
+	m.record("GetRoot")
+	if m.GetRootFunc == nil {
+		return nil, unexpectedCall("GetRoot")
+	}
+	return m.GetRootFunc(ctx)
+}
+
+// GetInfo records the call and returns the response of GetInfoFunc
+func (m *Mock) GetInfo() (*models.Info, error) {
+	return m.GetInfoWithContext(context.Background())
+}
+
+// GetInfoWithContext is like GetInfo but passes the context to GetInfoFunc
+func (m *Mock) GetInfoWithContext(ctx context.Context) (*models.Info, error) {
+	m.record("GetInfo")
+	if m.GetInfoFunc == nil {
+		return nil, unexpectedCall("GetInfo")
+	}
+	return m.GetInfoFunc(ctx)
+}
+
+// GetJob records the call and returns the response of GetJobFunc
+func (m *Mock) GetJob(jobGUID string) (*models.Job, error) {
+	return m.GetJobWithContext(context.Background(), jobGUID)
+}
+
+// GetJobWithContext is like GetJob but passes the context to GetJobFunc
+func (m *Mock) GetJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error) {
+	m.record("GetJob", jobGUID)
+	if m.GetJobFunc == nil {
+		return nil, unexpectedCall("GetJob")
+	}
+	return m.GetJobFunc(ctx, jobGUID)
+}
+
+// WaitForJob records the call and returns the response of WaitForJobFunc
+func (m *Mock) WaitForJob(jobGUID string) (*models.Job, error) {
+	return m.WaitForJobWithContext(context.Background(), jobGUID)
+}
+
+// WaitForJobWithContext is like WaitForJob but passes the context to WaitForJobFunc
+func (m *Mock) WaitForJobWithContext(ctx context.Context, jobGUID string) (*models.Job, error) {
+	m.record("WaitForJob", jobGUID)
+	if m.WaitForJobFunc == nil {
+		return nil, unexpectedCall("WaitForJob")
+	}
+	return m.WaitForJobFunc(ctx, jobGUID)
+}
+
+// ListOrganizations records the call and returns the response of ListOrganizationsFunc
+func (m *Mock) ListOrganizations(options cf.ListOrganizationsOptions) ([]models.Organization, error) {
+	return m.ListOrganizationsWithContext(context.Background(), options)
+}
+
+// ListOrganizationsWithContext is like ListOrganizations but passes the context to ListOrganizationsFunc
+func (m *Mock) ListOrganizationsWithContext(
+	ctx context.Context,
+	options cf.ListOrganizationsOptions,
+) ([]models.Organization, error) {
+	m.record("ListOrganizations", options)
+	if m.ListOrganizationsFunc == nil {
+		return nil, unexpectedCall("ListOrganizations")
+	}
+	return m.ListOrganizationsFunc(ctx, options)
+}
+
+// ListOrganizationsIterator records the call and returns the iterator of ListOrganizationsIteratorFunc.
+// Without ListOrganizationsIteratorFunc, the iterator returns the response of ListOrganizationsFunc as a single page
+func (m *Mock) ListOrganizationsIterator(options cf.ListOrganizationsOptions) *cf.PageIterator[models.Organization] {
+	m.record("ListOrganizationsIterator", options)
+	if m.ListOrganizationsIteratorFunc != nil {
+		return m.ListOrganizationsIteratorFunc(options)
+	}
+	return cf.NewPageIteratorFunc(func(ctx context.Context, _ int) ([]models.Organization, bool, error) {
+		if m.ListOrganizationsFunc == nil {
+			return nil, false, unexpectedCall("ListOrganizationsIterator")
+		}
+		resources, err := m.ListOrganizationsFunc(ctx, options)
+		return resources, false, err
+	})
+}
+
+// ListSpaces records the call and returns the response of ListSpacesFunc
+func (m *Mock) ListSpaces(options cf.ListSpacesOptions) ([]models.Space, error) {
+	return m.ListSpacesWithContext(context.Background(), options)
+}
+
+// ListSpacesWithContext is like ListSpaces but passes the context to ListSpacesFunc
+func (m *Mock) ListSpacesWithContext(ctx context.Context, options cf.ListSpacesOptions) ([]models.Space, error) {
+	m.record("ListSpaces", options)
+	if m.ListSpacesFunc == nil {
+		return nil, unexpectedCall("ListSpaces")
+	}
+	return m.ListSpacesFunc(ctx, options)
+}
+
+// ListSpacesIterator records the call and returns the iterator of ListSpacesIteratorFunc.
+// Without ListSpacesIteratorFunc, the iterator returns the response of ListSpacesFunc as a single page
+func (m *Mock) ListSpacesIterator(options cf.ListSpacesOptions) *cf.PageIterator[models.Space] {
+	m.record("ListSpacesIterator", options)
+	if m.ListSpacesIteratorFunc != nil {
+		return m.ListSpacesIteratorFunc(options)
+	}
+	return cf.NewPageIteratorFunc(func(ctx context.Context, _ int) ([]models.Space, bool, error) {
+		if m.ListSpacesFunc == nil {
+			return nil, false, unexpectedCall("ListSpacesIterator")
+		}
+		resources, err := m.ListSpacesFunc(ctx, options)
+		return resources, false, err
+	})
+}
+
+// ListSpacesEnriched records the call and returns the response of ListSpacesEnrichedFunc
+func (m *Mock) ListSpacesEnriched(options cf.ListSpacesOptions) ([]models.EnrichedSpace, error) {
+	return m.ListSpacesEnrichedWithContext(context.Background(), options)
+}
+
+// ListSpacesEnrichedWithContext is like ListSpacesEnriched but passes the context to ListSpacesEnrichedFunc
+func (m *Mock) ListSpacesEnrichedWithContext(
+	ctx context.Context,
+	options cf.ListSpacesOptions,
+) ([]models.EnrichedSpace, error) {
+	m.record("ListSpacesEnriched", options)
+	if m.ListSpacesEnrichedFunc == nil {
+		return nil, unexpectedCall("ListSpacesEnriched")
+	}
+	return m.ListSpacesEnrichedFunc(ctx, options)
+}
+
+// GetSpace records the call and returns the response of GetSpaceFunc
+func (m *Mock) GetSpace(guid string) (*models.Space, error) {
+	return m.GetSpaceWithContext(context.Background(), guid)
+}
+
+// GetSpaceWithContext is like GetSpace but passes the context to GetSpaceFunc
+func (m *Mock) GetSpaceWithContext(ctx context.Context, guid string) (*models.Space, error) {
+	m.record("GetSpace", guid)
+	if m.GetSpaceFunc == nil {
+		return nil, unexpectedCall("GetSpace")
+	}
+	return m.GetSpaceFunc(ctx, guid)
+}
+
+// GetSpaceEnriched records the call and returns the response of GetSpaceEnrichedFunc
+func (m *Mock) GetSpaceEnriched(guid string) (*models.EnrichedSpace, error) {
+	return m.GetSpaceEnrichedWithContext(context.Background(), guid)
+}
+
+// GetSpaceEnrichedWithContext is like GetSpaceEnriched but passes the context to GetSpaceEnrichedFunc
+func (m *Mock) GetSpaceEnrichedWithContext(ctx context.Context, guid string) (*models.EnrichedSpace, error) {
+	m.record("GetSpaceEnriched", guid)
+	if m.GetSpaceEnrichedFunc == nil {
+		return nil, unexpectedCall("GetSpaceEnriched")
+	}
+	return m.GetSpaceEnrichedFunc(ctx, guid)
+}
+
+// CreateSpace records the call and returns the response of CreateSpaceFunc
+func (m *Mock) CreateSpace(name, orgGUID string, options cf.CreateSpaceOptions) (*models.Space, error) {
+	return m.CreateSpaceWithContext(context.Background(), name, orgGUID, options)
+}
+
+// CreateSpaceWithContext is like CreateSpace but passes the context to CreateSpaceFunc
+func (m *Mock) CreateSpaceWithContext(
+	ctx context.Context,
+	name,
+	orgGUID string,
+	options cf.CreateSpaceOptions,
+) (*models.Space, error) {
+	m.record("CreateSpace", name, orgGUID, options)
+	if m.CreateSpaceFunc == nil {
+		return nil, unexpectedCall("CreateSpace")
+	}
+	return m.CreateSpaceFunc(ctx, name, orgGUID, options)
+}
+
+// UpdateSpace records the call and returns the response of UpdateSpaceFunc
+func (m *Mock) UpdateSpace(guid string, options cf.UpdateSpaceOptions) (*models.Space, error) {
+	return m.UpdateSpaceWithContext(context.Background(), guid, options)
+}
+
+// UpdateSpaceWithContext is like UpdateSpace but passes the context to UpdateSpaceFunc
+func (m *Mock) UpdateSpaceWithContext(
+	ctx context.Context,
+	guid string,
+	options cf.UpdateSpaceOptions,
+) (*models.Space, error) {
+	m.record("UpdateSpace", guid, options)
+	if m.UpdateSpaceFunc == nil {
+		return nil, unexpectedCall("UpdateSpace")
+	}
+	return m.UpdateSpaceFunc(ctx, guid, options)
+}
+
+// ListUsersForSpace records the call and returns the response of ListUsersForSpaceFunc
+func (m *Mock) ListUsersForSpace(spaceGUID string, options cf.ListUsersForSpaceOptions) ([]models.User, error) {
+	return m.ListUsersForSpaceWithContext(context.Background(), spaceGUID, options)
+}
+
+// ListUsersForSpaceWithContext is like ListUsersForSpace but passes the context to ListUsersForSpaceFunc
+func (m *Mock) ListUsersForSpaceWithContext(
+	ctx context.Context,
+	spaceGUID string,
+	options cf.ListUsersForSpaceOptions,
+) ([]models.User, error) {
+	m.record("ListUsersForSpace", spaceGUID, options)
+	if m.ListUsersForSpaceFunc == nil {
+		return nil, unexpectedCall("ListUsersForSpace")
+	}
+	return m.ListUsersForSpaceFunc(ctx, spaceGUID, options)
+}
+
+// ListUsersForSpaceIterator records the call and returns the iterator of ListUsersForSpaceIteratorFunc.
+// Without ListUsersForSpaceIteratorFunc, the iterator returns the response of ListUsersForSpaceFunc as a single page
+func (m *Mock) ListUsersForSpaceIterator(
+	spaceGUID string,
+	options cf.ListUsersForSpaceOptions,
+) *cf.PageIterator[models.User] {
+	m.record("ListUsersForSpaceIterator", spaceGUID, options)
+	if m.ListUsersForSpaceIteratorFunc != nil {
+		return m.ListUsersForSpaceIteratorFunc(spaceGUID, options)
+	}
+	return cf.NewPageIteratorFunc(func(ctx context.Context, _ int) ([]models.User, bool, error) {
+		if m.ListUsersForSpaceFunc == nil {
+			return nil, false, unexpectedCall("ListUsersForSpaceIterator")
+		}
+		resources, err := m.ListUsersForSpaceFunc(ctx, spaceGUID, options)
+		return resources, false, err
+	})
+}
+
+// ListUsers records the call and returns the response of ListUsersFunc
+func (m *Mock) ListUsers(options cf.ListUsersOptions) ([]models.User, error) {
+	return m.ListUsersWithContext(context.Background(), options)
+}
+
+// ListUsersWithContext is like ListUsers but passes the context to ListUsersFunc
+func (m *Mock) ListUsersWithContext(ctx context.Context, options cf.ListUsersOptions) ([]models.User, error) {
+	m.record("ListUsers", options)
+	if m.ListUsersFunc == nil {
+		return nil, unexpectedCall("ListUsers")
+	}
+	return m.ListUsersFunc(ctx, options)
+}
+
+// ListUsersIterator records the call and returns the iterator of ListUsersIteratorFunc.
+// Without ListUsersIteratorFunc, the iterator returns the response of ListUsersFunc as a single page
+func (m *Mock) ListUsersIterator(options cf.ListUsersOptions) *cf.PageIterator[models.User] {
+	m.record("ListUsersIterator", options)
+	if m.ListUsersIteratorFunc != nil {
+		return m.ListUsersIteratorFunc(options)
+	}
+	return cf.NewPageIteratorFunc(func(ctx context.Context, _ int) ([]models.User, bool, error) {
+		if m.ListUsersFunc == nil {
+			return nil, false, unexpectedCall("ListUsersIterator")
+		}
+		resources, err := m.ListUsersFunc(ctx, options)
+		return resources, false, err
+	})
+}
+
+// GetUser records the call and returns the response of GetUserFunc
+func (m *Mock) GetUser(userGUID string) (*models.User, error) {
+	return m.GetUserWithContext(context.Background(), userGUID)
+}
+
+// GetUserWithContext is like GetUser but passes the context to GetUserFunc
+func (m *Mock) GetUserWithContext(ctx context.Context, userGUID string) (*models.User, error) {
+	m.record("GetUser", userGUID)
+	if m.GetUserFunc == nil {
+		return nil, unexpectedCall("GetUser")
+	}
+	return m.GetUserFunc(ctx, userGUID)
+}
+
+// CreateUser records the call and returns the response of CreateUserFunc
+func (m *Mock) CreateUser(guid string, options cf.CreateUserOptions) (*models.User, error) {
+	return m.CreateUserWithContext(context.Background(), guid, options)
+}
+
+// CreateUserWithContext is like CreateUser but passes the context to CreateUserFunc
+func (m *Mock) CreateUserWithContext(
+	ctx context.Context,
+	guid string,
+	options cf.CreateUserOptions,
+) (*models.User, error) {
+	m.record("CreateUser", guid, options)
+	if m.CreateUserFunc == nil {
+		return nil, unexpectedCall("CreateUser")
+	}
+	return m.CreateUserFunc(ctx, guid, options)
+}
+
+// UpdateUser records the call and returns the response of UpdateUserFunc
+func (m *Mock) UpdateUser(guid string, metadata *models.MetadataPatch) (*models.User, error) {
+	return m.UpdateUserWithContext(context.Background(), guid, metadata)
+}
+
+// UpdateUserWithContext is like UpdateUser but passes the context to UpdateUserFunc
+func (m *Mock) UpdateUserWithContext(
+	ctx context.Context,
+	guid string,
+	metadata *models.MetadataPatch,
+) (*models.User, error) {
+	m.record("UpdateUser", guid, metadata)
+	if m.UpdateUserFunc == nil {
+		return nil, unexpectedCall("UpdateUser")
+	}
+	return m.UpdateUserFunc(ctx, guid, metadata)
+}
+
+// DeleteUser records the call and returns the response of DeleteUserFunc
+func (m *Mock) DeleteUser(userGUID string) (*cf.JobHandle, error) {
+	return m.DeleteUserWithContext(context.Background(), userGUID)
+}
+
+// DeleteUserWithContext is like DeleteUser but passes the context to DeleteUserFunc
+func (m *Mock) DeleteUserWithContext(ctx context.Context, userGUID string) (*cf.JobHandle, error) {
+	m.record("DeleteUser", userGUID)
+	if m.DeleteUserFunc == nil {
+		return nil, unexpectedCall("DeleteUser")
+	}
+	return m.DeleteUserFunc(ctx, userGUID)
+}
+
+// ListRole records the call and returns the response of ListRoleFunc
+func (m *Mock) ListRole(options cf.ListRoleOptions) ([]models.Role, error) {
+	return m.ListRoleWithContext(context.Background(), options)
+}
+
+// ListRoleWithContext is like ListRole but passes the context to ListRoleFunc
+func (m *Mock) ListRoleWithContext(ctx context.Context, options cf.ListRoleOptions) ([]models.Role, error) {
+	m.record("ListRole", options)
+	if m.ListRoleFunc == nil {
+		return nil, unexpectedCall("ListRole")
+	}
+	return m.ListRoleFunc(ctx, options)
+}
+
+// ListRoleIterator records the call and returns the iterator of ListRoleIteratorFunc.
+// Without ListRoleIteratorFunc, the iterator returns the response of ListRoleFunc as a single page
+func (m *Mock) ListRoleIterator(options cf.ListRoleOptions) *cf.PageIterator[models.Role] {
+	m.record("ListRoleIterator", options)
+	if m.ListRoleIteratorFunc != nil {
+		return m.ListRoleIteratorFunc(options)
+	}
+	return cf.NewPageIteratorFunc(func(ctx context.Context, _ int) ([]models.Role, bool, error) {
+		if m.ListRoleFunc == nil {
+			return nil, false, unexpectedCall("ListRoleIterator")
+		}
+		resources, err := m.ListRoleFunc(ctx, options)
+		return resources, false, err
+	})
+}
+
+// ListRoleEnriched records the call and returns the response of ListRoleEnrichedFunc
+func (m *Mock) ListRoleEnriched(options cf.ListRoleOptions) ([]models.EnrichedRole, error) {
+	return m.ListRoleEnrichedWithContext(context.Background(), options)
+}
+
+// ListRoleEnrichedWithContext is like ListRoleEnriched but passes the context to ListRoleEnrichedFunc
+func (m *Mock) ListRoleEnrichedWithContext(
+	ctx context.Context,
+	options cf.ListRoleOptions,
+) ([]models.EnrichedRole, error) {
+	m.record("ListRoleEnriched", options)
+	if m.ListRoleEnrichedFunc == nil {
+		return nil, unexpectedCall("ListRoleEnriched")
+	}
+	return m.ListRoleEnrichedFunc(ctx, options)
+}
+
+// GetRole records the call and returns the response of GetRoleFunc
+func (m *Mock) GetRole(roleGUID string) (*models.Role, error) {
+	return m.GetRoleWithContext(context.Background(), roleGUID)
+}
+
+// GetRoleWithContext is like GetRole but passes the context to GetRoleFunc
+func (m *Mock) GetRoleWithContext(ctx context.Context, roleGUID string) (*models.Role, error) {
+	m.record("GetRole", roleGUID)
+	if m.GetRoleFunc == nil {
+		return nil, unexpectedCall("GetRole")
+	}
+	return m.GetRoleFunc(ctx, roleGUID)
+}
+
+// GetRoleEnriched records the call and returns the response of GetRoleEnrichedFunc
+func (m *Mock) GetRoleEnriched(roleGUID string, include ...cf.Include) (*models.EnrichedRole, error) {
+	return m.GetRoleEnrichedWithContext(context.Background(), roleGUID, include...)
+}
+
+// GetRoleEnrichedWithContext is like GetRoleEnriched but passes the context to GetRoleEnrichedFunc
+func (m *Mock) GetRoleEnrichedWithContext(
+	ctx context.Context,
+	roleGUID string,
+	include ...cf.Include,
+) (*models.EnrichedRole, error) {
+	m.record("GetRoleEnriched", roleGUID, include)
+	if m.GetRoleEnrichedFunc == nil {
+		return nil, unexpectedCall("GetRoleEnriched")
+	}
+	return m.GetRoleEnrichedFunc(ctx, roleGUID, include...)
+}
+
+// CreateRole records the call and returns the response of CreateRoleFunc
+func (m *Mock) CreateRole(
+	role cf.Role,
+	spaceOrOrganizationGUID string,
+	options cf.CreateRoleOptions,
+) (*models.Role, error) {
+	return m.CreateRoleWithContext(context.Background(), role, spaceOrOrganizationGUID, options)
+}
+
+// CreateRoleWithContext is like CreateRole but passes the context to CreateRoleFunc
+func (m *Mock) CreateRoleWithContext(
+	ctx context.Context,
+	role cf.Role,
+	spaceOrOrganizationGUID string,
+	options cf.CreateRoleOptions,
+) (*models.Role, error) {
+	m.record("CreateRole", role, spaceOrOrganizationGUID, options)
+	if m.CreateRoleFunc == nil {
+		return nil, unexpectedCall("CreateRole")
+	}
+	return m.CreateRoleFunc(ctx, role, spaceOrOrganizationGUID, options)
+}
+
+// DeleteRole records the call and returns the response of DeleteRoleFunc
+func (m *Mock) DeleteRole(roleGUID string) (*cf.JobHandle, error) {
+	return m.DeleteRoleWithContext(context.Background(), roleGUID)
+}
+
+// DeleteRoleWithContext is like DeleteRole but passes the context to DeleteRoleFunc
+func (m *Mock) DeleteRoleWithContext(ctx context.Context, roleGUID string) (*cf.JobHandle, error) {
+	m.record("DeleteRole", roleGUID)
+	if m.DeleteRoleFunc == nil {
+		return nil, unexpectedCall("DeleteRole")
+	}
+	return m.DeleteRoleFunc(ctx, roleGUID)
+}
@@ -0,0 +1,121 @@
+package cftest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/cftest"
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+// contextKey is the type of context values used to check that contexts are passed on
+type contextKey struct{}
+
+func TestMockRecordsCalls(t *testing.T) {
+	mock := &cftest.Mock{
+		GetSpaceFunc: func(ctx context.Context, guid string) (*models.Space, error) {
+			return &models.Space{Guid: guid, Name: fmt.Sprint(ctx.Value(contextKey{}))}, nil
+		},
+	}
+	if space, err := mock.GetSpace("space-1"); err != nil || space.Guid != "space-1" {
+		t.Fatalf("space = %v, err = %v, want the programmed space", space, err)
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "dev")
+	if space, err := mock.GetSpaceWithContext(ctx, "space-2"); err != nil || space.Name != "dev" {
+		t.Fatalf("space = %v, err = %v, want the context to be passed to the function", space, err)
+	}
+	_, _ = mock.ListUsersForSpace("space-1", cf.ListUsersForSpaceOptions{})
+
+	want := []cftest.Call{
+		{Method: "GetSpace", Args: []any{"space-1"}},
+		{Method: "GetSpace", Args: []any{"space-2"}},
+		{Method: "ListUsersForSpace", Args: []any{"space-1", cf.ListUsersForSpaceOptions{}}},
+	}
+	if got := mock.Calls(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if got := mock.CallsTo("GetSpace"); len(got) != 2 {
+		t.Errorf("calls to GetSpace = %v, want 2 calls", got)
+	}
+
+	mock.Reset()
+	if got := mock.Calls(); len(got) != 0 {
+		t.Errorf("calls = %v, want no calls after Reset", got)
+	}
+}
+
+func TestMockReturnsUnexpectedCallErr(t *testing.T) {
+	mock := &cftest.Mock{}
+	for name, call := range map[string]func() error{
+		"SendRequest": func() error {
+			_, err := mock.SendRequest("GET", "/v3/spaces")
+			return err
+		},
+		"GetRoot": func() error {
+			_, err := mock.GetRoot()
+			return err
+		},
+		"ListSpaces": func() error {
+			_, err := mock.ListSpaces(cf.ListSpacesOptions{})
+			return err
+		},
+		"ListSpacesIterator": func() error {
+			_, err := mock.ListSpacesIterator(cf.ListSpacesOptions{}).Collect(context.Background())
+			return err
+		},
+		"CreateUser": func() error {
+			_, err := mock.CreateUserWithContext(context.Background(), "user-guid", cf.CreateUserOptions{})
+			return err
+		},
+		"DeleteRole": func() error {
+			_, err := mock.DeleteRole("role-guid")
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := call(); !errors.Is(err, cftest.UnexpectedCallErr) {
+				t.Errorf("err = %v, want UnexpectedCallErr", err)
+			}
+			if got := mock.CallsTo(name); len(got) != 1 {
+				t.Errorf("calls to %s = %v, want the unexpected call to be recorded", name, got)
+			}
+		})
+	}
+}
+
+func TestMockIteratorFallsBackToListFunc(t *testing.T) {
+	options := cf.ListSpacesOptions{Names: []string{"dev"}}
+	mock := &cftest.Mock{
+		ListSpacesFunc: func(ctx context.Context, got cf.ListSpacesOptions) ([]models.Space, error) {
+			if fmt.Sprint(got) != fmt.Sprint(options) {
+				t.Errorf("options = %+v, want %+v", got, options)
+			}
+			return []models.Space{{Name: "dev"}, {Name: fmt.Sprint(ctx.Value(contextKey{}))}}, nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "from-context")
+	spaces, err := mock.ListSpacesIterator(options).Collect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(spaces); fmt.Sprint(got) != "[dev from-context]" {
+		t.Errorf("spaces = %v, want the spaces of ListSpacesFunc", got)
+	}
+	if calls := mock.CallsTo("ListSpacesIterator"); len(calls) != 1 {
+		t.Errorf("calls to ListSpacesIterator = %v, want 1 call", calls)
+	}
+
+	// an iterator function takes precedence over the list function
+	mock.ListSpacesIteratorFunc = func(cf.ListSpacesOptions) *cf.PageIterator[models.Space] {
+		return cf.NewPageIteratorFunc(func(context.Context, int) ([]models.Space, bool, error) {
+			return []models.Space{{Name: "from-iterator"}}, false, nil
+		})
+	}
+	spaces, err = mock.ListSpacesIterator(options).Collect(ctx)
+	if err != nil || fmt.Sprint(names(spaces)) != "[from-iterator]" {
+		t.Errorf("spaces = %v, err = %v, want the spaces of ListSpacesIteratorFunc", names(spaces), err)
+	}
+}
@@ -0,0 +1,537 @@
+package cftest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/darmiel/go-cf-client/pkg/labels"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// roleTypes are the valid types of roles
+var roleTypes = []string{
+	"organization_user", "organization_auditor", "organization_manager", "organization_billing_manager",
+	"space_auditor", "space_developer", "space_manager", "space_supporter",
+}
+
+// organization returns the organization with the given GUID or nil. s.mu must be held by the caller
+func (s *Server) organization(guid string) *models.Organization {
+	idx := slices.IndexFunc(s.organizations, func(o models.Organization) bool { return o.Guid == guid })
+	if idx < 0 {
+		return nil
+	}
+	return &s.organizations[idx]
+}
+
+// space returns the space with the given GUID or nil. s.mu must be held by the caller
+func (s *Server) space(guid string) *models.Space {
+	idx := slices.IndexFunc(s.spaces, func(sp models.Space) bool { return sp.Guid == guid })
+	if idx < 0 {
+		return nil
+	}
+	return &s.spaces[idx]
+}
+
+// user returns the user with the given GUID or nil. s.mu must be held by the caller
+func (s *Server) user(guid string) *models.User {
+	idx := slices.IndexFunc(s.ccUsers, func(u models.User) bool { return u.Guid == guid })
+	if idx < 0 {
+		return nil
+	}
+	return &s.ccUsers[idx]
+}
+
+// role returns the role with the given GUID or nil. s.mu must be held by the caller
+func (s *Server) role(guid string) *models.Role {
+	idx := slices.IndexFunc(s.roles, func(r models.Role) bool { return r.Guid == guid })
+	if idx < 0 {
+		return nil
+	}
+	return &s.roles[idx]
+}
+
+// organizationKind describes how organizations are listed
+func (s *Server) organizationKind() kind[models.Organization] {
+	return kind[models.Organization]{
+		meta: func(o models.Organization) meta {
+			return meta{guid: o.Guid, name: o.Name, createdAt: o.CreatedAt, updatedAt: o.UpdatedAt, labels: o.Metadata.Labels}
+		},
+		named: true,
+		filters: map[string]filter[models.Organization]{
+			"guids": field(func(o models.Organization) string { return o.Guid }),
+		},
+	}
+}
+
+// spaceKind describes how spaces are listed
+func (s *Server) spaceKind() kind[models.Space] {
+	return kind[models.Space]{
+		meta: func(sp models.Space) meta {
+			return meta{guid: sp.Guid, name: sp.Name, createdAt: sp.CreatedAt, updatedAt: sp.UpdatedAt, labels: sp.Metadata.Labels}
+		},
+		named: true,
+		filters: map[string]filter[models.Space]{
+			"guids":              field(func(sp models.Space) string { return sp.Guid }),
+			"organization_guids": field(func(sp models.Space) string { return sp.Relationships.Organization.Data.Guid }),
+		},
+		includes: []string{"organization"},
+		include: func(spaces []models.Space, _ []string) models.Included {
+			var included models.Included
+			for _, sp := range spaces {
+				if org := s.organization(sp.Relationships.Organization.Data.Guid); org != nil {
+					included.Append(models.Included{Organizations: []models.Organization{*org}})
+				}
+			}
+			return included
+		},
+	}
+}
+
+// userKind describes how users are listed
+func (s *Server) userKind() kind[models.User] {
+	return kind[models.User]{
+		meta: func(u models.User) meta {
+			return meta{guid: u.Guid, createdAt: u.CreatedAt, updatedAt: u.UpdatedAt, labels: u.Metadata.Labels}
+		},
+		filters: map[string]filter[models.User]{
+			"guids":     field(func(u models.User) string { return u.Guid }),
+			"usernames": field(func(u models.User) string { return u.Username }),
+			"origins":   field(func(u models.User) string { return u.Origin }),
+			"partial_usernames": func(u models.User, values []string) bool {
+				return slices.ContainsFunc(values, func(value string) bool {
+					return strings.Contains(u.Username, value)
+				})
+			},
+		},
+	}
+}
+
+// roleKind describes how roles are listed
+func (s *Server) roleKind() kind[models.Role] {
+	return kind[models.Role]{
+		meta: func(r models.Role) meta {
+			return meta{guid: r.Guid, createdAt: r.CreatedAt, updatedAt: r.UpdatedAt}
+		},
+		filters: map[string]filter[models.Role]{
+			"guids":              field(func(r models.Role) string { return r.Guid }),
+			"types":              field(func(r models.Role) string { return r.Type }),
+			"user_guids":         field(models.Role.GetUserID),
+			"space_guids":        field(func(r models.Role) string { return r.Relationships.Space.Data.Guid }),
+			"organization_guids": field(func(r models.Role) string { return r.Relationships.Organization.Data.Guid }),
+		},
+		includes: []string{"user", "space", "organization"},
+		include: func(roles []models.Role, includes []string) models.Included {
+			var included models.Included
+			for _, r := range roles {
+				if slices.Contains(includes, "user") {
+					if u := s.user(r.GetUserID()); u != nil {
+						included.Append(models.Included{Users: []models.User{*u}})
+					}
+				}
+				orgGUID := r.Relationships.Organization.Data.Guid
+				if sp := s.space(r.Relationships.Space.Data.Guid); sp != nil {
+					if slices.Contains(includes, "space") {
+						included.Append(models.Included{Spaces: []models.Space{*sp}})
+					}
+					orgGUID = sp.Relationships.Organization.Data.Guid
+				}
+				if slices.Contains(includes, "organization") {
+					if org := s.organization(orgGUID); org != nil {
+						included.Append(models.Included{Organizations: []models.Organization{*org}})
+					}
+				}
+			}
+			return included
+		},
+	}
+}
+
+// handleListOrganizations lists the organizations
+func (s *Server) handleListOrganizations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(s, w, r, s.organizationKind(), slices.Clone(s.organizations))
+}
+
+// handleGetOrganization returns an organization
+func (s *Server) handleGetOrganization(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org := s.organization(r.PathValue("guid"))
+	if org == nil {
+		writeNotFound(w, "Organization")
+		return
+	}
+	writeResource(w, r, s.organizationKind(), *org)
+}
+
+// handleListSpaces lists the spaces
+func (s *Server) handleListSpaces(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(s, w, r, s.spaceKind(), slices.Clone(s.spaces))
+}
+
+// handleGetSpace returns a space
+func (s *Server) handleGetSpace(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	space := s.space(r.PathValue("guid"))
+	if space == nil {
+		writeNotFound(w, "Space")
+		return
+	}
+	writeResource(w, r, s.spaceKind(), *space)
+}
+
+// handleCreateSpace creates a space
+func (s *Server) handleCreateSpace(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name          string `json:"name"`
+		Relationships struct {
+			Organization relationship `json:"organization"`
+		} `json:"relationships"`
+		Metadata models.Metadata `json:"metadata"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusUnprocessableEntity, 10008, "CF-UnprocessableEntity", "Name can't be blank")
+		return
+	}
+	if !validMetadata(w, body.Metadata.Patch()) {
+		return
+	}
+
+	var space models.Space
+	space.Name = body.Name
+	space.Relationships.Organization.Data.Guid = body.Relationships.Organization.Data.Guid
+	space.Metadata = body.Metadata
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	space, err := s.addSpaceLocked(space)
+	if err != nil {
+		writeConflict(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, space)
+}
+
+// handleUpdateSpace updates the name and metadata of a space
+func (s *Server) handleUpdateSpace(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string                `json:"name"`
+		Metadata *models.MetadataPatch `json:"metadata"`
+	}
+	if !decodeBody(w, r, &body) || !validMetadata(w, body.Metadata) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	space := s.space(r.PathValue("guid"))
+	if space == nil {
+		writeNotFound(w, "Space")
+		return
+	}
+	if body.Name != "" && !strings.EqualFold(body.Name, space.Name) {
+		for _, other := range s.spaces {
+			if other.Relationships.Organization.Data.Guid == space.Relationships.Organization.Data.Guid &&
+				strings.EqualFold(other.Name, body.Name) {
+				writeError(w, http.StatusUnprocessableEntity, 10016, "CF-UniquenessError",
+					"Name must be unique per organization")
+				return
+			}
+		}
+	}
+	if body.Name != "" {
+		space.Name = body.Name
+	}
+	applyMetadata(&space.Metadata, body.Metadata)
+	space.UpdatedAt = time.Now().UTC()
+	writeJSON(w, http.StatusOK, *space)
+}
+
+// handleDeleteSpace starts a job which deletes a space and its roles
+func (s *Server) handleDeleteSpace(w http.ResponseWriter, r *http.Request) {
+	guid := r.PathValue("guid")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.space(guid) == nil {
+		writeNotFound(w, "Space")
+		return
+	}
+	s.startJobLocked(w, "space.delete", func() {
+		s.spaces = slices.DeleteFunc(s.spaces, func(sp models.Space) bool { return sp.Guid == guid })
+		s.roles = slices.DeleteFunc(s.roles, func(role models.Role) bool { return role.Relationships.Space.Data.Guid == guid })
+	})
+}
+
+// handleListSpaceUsers lists the users which have a role in a space
+func (s *Server) handleListSpaceUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	guid := r.PathValue("guid")
+	if s.space(guid) == nil {
+		writeNotFound(w, "Space")
+		return
+	}
+	var users []models.User
+	for _, u := range s.ccUsers {
+		if slices.ContainsFunc(s.roles, func(role models.Role) bool {
+			return role.GetUserID() == u.Guid && role.Relationships.Space.Data.Guid == guid
+		}) {
+			users = append(users, u)
+		}
+	}
+	writeList(s, w, r, s.userKind(), users)
+}
+
+// handleListUsers lists the users
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(s, w, r, s.userKind(), slices.Clone(s.ccUsers))
+}
+
+// handleGetUser returns a user
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(r.PathValue("guid"))
+	if u == nil {
+		writeNotFound(w, "User")
+		return
+	}
+	writeResource(w, r, s.userKind(), *u)
+}
+
+// handleCreateUser creates a user
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Guid     string          `json:"guid"`
+		Username string          `json:"username"`
+		Origin   string          `json:"origin"`
+		Metadata models.Metadata `json:"metadata"`
+	}
+	if !decodeBody(w, r, &body) || !validMetadata(w, body.Metadata.Patch()) {
+		return
+	}
+	if body.Guid == "" && body.Username == "" {
+		writeError(w, http.StatusUnprocessableEntity, 10008, "CF-UnprocessableEntity", "Guid or username must be provided")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.addUserLocked(models.User{
+		Guid:     body.Guid,
+		Username: body.Username,
+		Origin:   body.Origin,
+		Metadata: body.Metadata,
+	})
+	if err != nil {
+		writeConflict(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, u)
+}
+
+// handleUpdateUser updates the metadata of a user
+func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Metadata *models.MetadataPatch `json:"metadata"`
+	}
+	if !decodeBody(w, r, &body) || !validMetadata(w, body.Metadata) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(r.PathValue("guid"))
+	if u == nil {
+		writeNotFound(w, "User")
+		return
+	}
+	applyMetadata(&u.Metadata, body.Metadata)
+	u.UpdatedAt = time.Now().UTC()
+	writeJSON(w, http.StatusOK, *u)
+}
+
+// handleDeleteUser starts a job which deletes a user and its roles
+func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	guid := r.PathValue("guid")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.user(guid) == nil {
+		writeNotFound(w, "User")
+		return
+	}
+	s.startJobLocked(w, "user.delete", func() {
+		s.ccUsers = slices.DeleteFunc(s.ccUsers, func(u models.User) bool { return u.Guid == guid })
+		s.roles = slices.DeleteFunc(s.roles, func(role models.Role) bool { return role.GetUserID() == guid })
+	})
+}
+
+// handleListRoles lists the roles
+func (s *Server) handleListRoles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(s, w, r, s.roleKind(), slices.Clone(s.roles))
+}
+
+// handleGetRole returns a role
+func (s *Server) handleGetRole(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role := s.role(r.PathValue("guid"))
+	if role == nil {
+		writeNotFound(w, "Role")
+		return
+	}
+	writeResource(w, r, s.roleKind(), *role)
+}
+
+// handleCreateRole creates a role for a user which is referenced by GUID or by username
+func (s *Server) handleCreateRole(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Type          string `json:"type"`
+		Relationships struct {
+			User struct {
+				Data struct {
+					Guid     string `json:"guid"`
+					Username string `json:"username"`
+					Origin   string `json:"origin"`
+				} `json:"data"`
+			} `json:"user"`
+			Organization relationship `json:"organization"`
+			Space        relationship `json:"space"`
+		} `json:"relationships"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !slices.Contains(roleTypes, body.Type) {
+		writeError(w, http.StatusUnprocessableEntity, 10008, "CF-UnprocessableEntity",
+			"Type must be one of the allowed types "+strings.Join(roleTypes, ", "))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userData := body.Relationships.User.Data
+	if userData.Guid == "" && userData.Username != "" {
+		idx := slices.IndexFunc(s.ccUsers, func(u models.User) bool {
+			return u.Username == userData.Username && (userData.Origin == "" || u.Origin == userData.Origin)
+		})
+		if idx < 0 {
+			writeError(w, http.StatusUnprocessableEntity, 10008, "CF-UnprocessableEntity",
+				"No user exists with the username '"+userData.Username+"'.")
+			return
+		}
+		userData.Guid = s.ccUsers[idx].Guid
+	}
+
+	var role models.Role
+	role.Type = body.Type
+	role.Relationships.User.Data.Guid = userData.Guid
+	role.Relationships.Organization.Data.Guid = body.Relationships.Organization.Data.Guid
+	role.Relationships.Space.Data.Guid = body.Relationships.Space.Data.Guid
+	role, err := s.addRoleLocked(role)
+	if err != nil {
+		writeConflict(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, role)
+}
+
+// handleDeleteRole starts a job which deletes a role
+func (s *Server) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	guid := r.PathValue("guid")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.role(guid) == nil {
+		writeNotFound(w, "Role")
+		return
+	}
+	s.startJobLocked(w, "role.delete", func() {
+		s.roles = slices.DeleteFunc(s.roles, func(role models.Role) bool { return role.Guid == guid })
+	})
+}
+
+// relationship is a to-one relationship of a request body
+type relationship struct {
+	Data struct {
+		Guid string `json:"guid"`
+	} `json:"data"`
+}
+
+// decodeBody decodes the JSON body of the request into value.
+// If the body is invalid, a CF-MessageParseError is written and false is returned
+func decodeBody(w http.ResponseWriter, r *http.Request, value any) bool {
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		writeError(w, http.StatusBadRequest, 1001, "CF-MessageParseError",
+			"Request invalid due to parse error: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// validMetadata validates the label keys and values of the patch.
+// If a label is invalid, a CF-UnprocessableEntity error is written and false is returned
+func validMetadata(w http.ResponseWriter, patch *models.MetadataPatch) bool {
+	if patch == nil {
+		return true
+	}
+	var errs []error
+	for key, value := range patch.Labels {
+		errs = append(errs, labels.ValidateKey(key))
+		if value != nil {
+			errs = append(errs, labels.ValidateValue(*value))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, 10008, "CF-UnprocessableEntity", "Metadata "+err.Error())
+		return false
+	}
+	return true
+}
+
+// applyMetadata applies the patch to the metadata. Keys with a nil value are deleted
+func applyMetadata(metadata *models.Metadata, patch *models.MetadataPatch) {
+	if patch == nil {
+		return
+	}
+	normalizeMetadata(metadata)
+	apply := func(target map[string]string, changes map[string]*string) {
+		for key, value := range changes {
+			if value == nil {
+				delete(target, key)
+			} else {
+				target[key] = *value
+			}
+		}
+	}
+	apply(metadata.Labels, patch.Labels)
+	apply(metadata.Annotations, patch.Annotations)
+}
+
+// writeNotFound writes a CF-ResourceNotFound error for the given resource type
+func writeNotFound(w http.ResponseWriter, resource string) {
+	writeError(w, http.StatusNotFound, 10010, "CF-ResourceNotFound", resource+" not found")
+}
+
+// writeConflict writes the error returned by adding a resource
+func writeConflict(w http.ResponseWriter, err error) {
+	var conflict *conflictError
+	if errors.As(err, &conflict) {
+		writeError(w, http.StatusUnprocessableEntity, conflict.code, conflict.title, conflict.detail)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, 10001, "UnknownError", err.Error())
+}
@@ -0,0 +1,189 @@
+package cftest
+
+import (
+	"encoding/json"
+	"github.com/darmiel/go-cf-client/pkg/cf"
+	"github.com/darmiel/go-cf-client/pkg/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleToken issues tokens for the password (including passcodes), refresh_token, client_credentials
+// and JWT bearer grants
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if clientID != s.clientID || clientSecret != s.clientSecret {
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "Bad client credentials")
+		return
+	}
+
+	grant := r.Form.Get("grant_type")
+	refreshable := true
+	switch grant {
+	case string(cf.PasswordGrant):
+		if passcode := r.Form.Get("passcode"); passcode != "" {
+			if _, ok := s.passcodes[passcode]; !ok {
+				writeTokenError(w, http.StatusUnauthorized, "unauthorized", "Bad credentials")
+				return
+			}
+			delete(s.passcodes, passcode)
+			break
+		}
+		if !s.validUserLocked(r.Form.Get("username"), r.Form.Get("password")) {
+			writeTokenError(w, http.StatusUnauthorized, "unauthorized", "Bad credentials")
+			return
+		}
+	case "refresh_token":
+		token := r.Form.Get("refresh_token")
+		if _, ok := s.refreshTokens[token]; !ok {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_token", "Invalid refresh token: "+token)
+			return
+		}
+		delete(s.refreshTokens, token)
+	case string(cf.JWTBearerGrant):
+		if _, ok := s.assertions[r.Form.Get("assertion")]; !ok {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_grant", "Invalid assertion")
+			return
+		}
+	case string(cf.ClientCredentialsGrant):
+		// the UAA doesn't issue refresh tokens for the client credentials grant
+		refreshable = false
+	default:
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type: "+grant)
+		return
+	}
+	s.grants = append(s.grants, grant)
+
+	info := cf.AuthTokenInfo{
+		AccessToken: newToken(),
+		TokenType:   "bearer",
+		ExpiresIn:   int(s.tokenExpiry.Seconds()),
+		Scope:       "cloud_controller.admin openid",
+		JTI:         newGUID(),
+	}
+	s.accessTokens[info.AccessToken] = time.Now().Add(s.tokenExpiry)
+	if refreshable {
+		info.RefreshToken = newToken()
+		s.refreshTokens[info.RefreshToken] = struct{}{}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// validUserLocked returns true if a UAA user with the given credentials exists. s.mu must be held by the caller
+func (s *Server) validUserLocked(username, password string) bool {
+	for _, user := range s.users {
+		if user.username == username && user.password == password {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticated returns a handler which only calls next if the request has a valid, unexpired access token
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+			writeError(w, http.StatusUnauthorized, cf.NotAuthenticatedErrorCode,
+				"CF-NotAuthenticated", "Authentication error")
+			return
+		}
+		s.mu.Lock()
+		expiry, ok := s.accessTokens[token]
+		s.mu.Unlock()
+		if !ok || !expiry.After(time.Now()) {
+			writeError(w, http.StatusUnauthorized, cf.InvalidAuthTokenErrorCode,
+				"CF-InvalidAuthToken", "Invalid Auth Token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ExpireTokens expires all issued access tokens, so the next request of a client is rejected with
+// CF-InvalidAuthToken and the client has to refresh its token. Refresh tokens stay valid
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.accessTokens {
+		s.accessTokens[token] = time.Time{}
+	}
+}
+
+// RevokeTokens revokes all issued access and refresh tokens, so clients have to authenticate again
+// using their original grant
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.accessTokens)
+	clear(s.refreshTokens)
+}
+
+// TokenGrants returns the grant types of all tokens issued by the UAA in the order they were issued,
+// e.g. ["password", "refresh_token"]
+func (s *Server) TokenGrants() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.grants...)
+}
+
+// handleRoot serves the root document which links the UAA and the login server to the server itself
+func (s *Server) handleRoot(w http.ResponseWriter, _ *http.Request) {
+	link := func(href string) *models.RootLink {
+		return &models.RootLink{Href: href}
+	}
+	var root models.Root
+	root.Links.Self = link(s.URL)
+	root.Links.CloudControllerV3 = link(s.URL + "/v3")
+	root.Links.CloudControllerV3.Meta.Version = "3.180.0"
+	root.Links.Login = link(s.URL)
+	root.Links.UAA = link(s.URL)
+	writeJSON(w, http.StatusOK, root)
+}
+
+// handleInfo serves the information about the fake foundation
+func (s *Server) handleInfo(w http.ResponseWriter, _ *http.Request) {
+	info := models.Info{
+		Name:        "cftest",
+		Description: "In-memory fake Cloud Controller",
+		Version:     1,
+	}
+	info.Links.Self.Href = s.URL + "/v3/info"
+	writeJSON(w, http.StatusOK, info)
+}
+
+// writeTokenError writes an OAuth error response of the UAA
+func writeTokenError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// writeJSON writes the value as JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes a CF-style error response containing a single error
+func writeError(w http.ResponseWriter, status, code int, title, detail string) {
+	writeErrors(w, status, cf.CloudFoundryError{Code: code, Title: title, Detail: detail})
+}
+
+// writeErrors writes a CF-style error response containing the given errors
+func writeErrors(w http.ResponseWriter, status int, errs ...cf.CloudFoundryError) {
+	writeJSON(w, status, map[string]any{"errors": errs})
+}
@@ -0,0 +1,186 @@
+package labels_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/labels"
+)
+
+// prefix returns a DNS prefix with the given length made of labels with at most 63 characters
+func prefix(length int) string {
+	var parts []string
+	for length > 0 {
+		n := min(length, labels.MaxNameLength)
+		parts = append(parts, strings.Repeat("a", n))
+		length -= n + 1
+	}
+	return strings.Join(parts, ".")
+}
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"env", true},
+		{"a", true},
+		{"team.name_1-x", true},
+		{"example.com/team", true},
+		{strings.Repeat("a", labels.MaxNameLength), true},
+		{prefix(labels.MaxPrefixLength) + "/team", true},
+		{"", false},
+		{strings.Repeat("a", labels.MaxNameLength+1), false},
+		{prefix(labels.MaxPrefixLength+1) + "/team", false},
+		{strings.Repeat("a", labels.MaxNameLength+1) + ".com/team", false},
+		{"example.com/", false},
+		{"/team", false},
+		{"ex_ample.com/team", false},
+		{"-env", false},
+		{"env-", false},
+		{"env name", false},
+		{"a/b/c", false},
+	}
+	for _, tt := range tests {
+		err := labels.ValidateKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateKey(%q) = %v, want nil", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, labels.InvalidKeyErr) {
+			t.Errorf("ValidateKey(%q) = %v, want InvalidKeyErr", tt.key, err)
+		}
+	}
+	if len(prefix(labels.MaxPrefixLength)) != labels.MaxPrefixLength {
+		t.Fatalf("prefix has length %d, want %d", len(prefix(labels.MaxPrefixLength)), labels.MaxPrefixLength)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []labels.Requirement
+		str      string
+	}{
+		{"", nil, ""},
+		{"env=prod", []labels.Requirement{{Key: "env", Operator: labels.Equals, Values: []string{"prod"}}}, "env=prod"},
+		{"env == prod", []labels.Requirement{{Key: "env", Operator: labels.Equals, Values: []string{"prod"}}}, "env=prod"},
+		{"env!=prod", []labels.Requirement{{Key: "env", Operator: labels.NotEquals, Values: []string{"prod"}}}, "env!=prod"},
+		{"env=", []labels.Requirement{{Key: "env", Operator: labels.Equals, Values: []string{""}}}, "env="},
+		{
+			"env in (prod, staging)",
+			[]labels.Requirement{{Key: "env", Operator: labels.In, Values: []string{"prod", "staging"}}},
+			"env in (prod,staging)",
+		},
+		{
+			"tier notin (free)",
+			[]labels.Requirement{{Key: "tier", Operator: labels.NotIn, Values: []string{"free"}}},
+			"tier notin (free)",
+		},
+		{"example.com/owner", []labels.Requirement{{Key: "example.com/owner", Operator: labels.Exists}}, "example.com/owner"},
+		{"!deprecated", []labels.Requirement{{Key: "deprecated", Operator: labels.DoesNotExist}}, "!deprecated"},
+		{
+			"env=prod, tier notin (free,trial),owner,!deprecated",
+			[]labels.Requirement{
+				{Key: "env", Operator: labels.Equals, Values: []string{"prod"}},
+				{Key: "tier", Operator: labels.NotIn, Values: []string{"free", "trial"}},
+				{Key: "owner", Operator: labels.Exists},
+				{Key: "deprecated", Operator: labels.DoesNotExist},
+			},
+			"env=prod,tier notin (free,trial),owner,!deprecated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := selector.Requirements(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requirements = %#v, want %#v", got, tt.want)
+			}
+			if got := selector.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+
+			// the string of a selector parses to the same requirements
+			parsed, err := labels.Parse(selector.String())
+			if err != nil || !reflect.DeepEqual(parsed.Requirements(), selector.Requirements()) {
+				t.Errorf("round trip of %q = %v (%v), want %v", selector, parsed.Requirements(), err, selector.Requirements())
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidSelectors(t *testing.T) {
+	for _, selector := range []string{
+		"env=prod,",
+		",env",
+		"env in ()",
+		"env in (prod",
+		"(env)",
+		"-env",
+		"env=-prod",
+		"env!=a b",
+		"env in (prod,-staging)",
+		strings.Repeat("a", labels.MaxNameLength+1),
+		prefix(labels.MaxPrefixLength+1) + "/env=prod",
+		"env=" + strings.Repeat("a", labels.MaxNameLength+1),
+	} {
+		if _, err := labels.Parse(selector); !errors.Is(err, labels.InvalidSelectorErr) {
+			t.Errorf("Parse(%q) = %v, want InvalidSelectorErr", selector, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	resource := map[string]string{"env": "prod", "owner": "team-a"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"missing=prod", false},
+		{"env!=dev", true},
+		{"env!=prod", false},
+		{"missing!=prod", true},
+		{"env in (dev,prod)", true},
+		{"env in (dev,staging)", false},
+		{"missing in (prod)", false},
+		{"env notin (dev,staging)", true},
+		{"env notin (prod)", false},
+		{"missing notin (prod)", true},
+		{"owner", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!owner", false},
+		{"env=prod,owner,!deprecated", true},
+		{"env=prod,!owner", false},
+	}
+	for _, tt := range tests {
+		if got := labels.MustParse(tt.selector).Matches(resource); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.selector, resource, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorBuilder(t *testing.T) {
+	selector := labels.New().Eq("env", "prod").NotEq("tier", "free").In("region", "eu", "us").
+		NotIn("zone", "a").Exists("owner").NotExists("deprecated")
+	want := "env=prod,tier!=free,region in (eu,us),zone notin (a),owner,!deprecated"
+	if got, err := selector.Build(); err != nil || got != want {
+		t.Errorf("Build() = %q, %v, want %q", got, err, want)
+	}
+
+	// invalid requirements are not added, and the first error is kept
+	selector = labels.New().Eq("env", "prod").Eq("-invalid", "x").In("tier").Exists("owner")
+	if _, err := selector.Build(); !errors.Is(err, labels.InvalidSelectorErr) || !errors.Is(err, labels.InvalidKeyErr) {
+		t.Errorf("Build() = %v, want an invalid key", err)
+	}
+	if got := selector.String(); got != "env=prod,owner" {
+		t.Errorf("String() = %q, want the valid requirements", got)
+	}
+}
@@ -0,0 +1,207 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxPrefixLength is the maximum length of the DNS prefix of a label key
+	MaxPrefixLength = 253
+
+	// MaxNameLength is the maximum length of the name of a label key and of a label value
+	MaxNameLength = 63
+)
+
+var (
+	InvalidSelectorErr = errors.New("invalid label selector")
+	InvalidKeyErr      = errors.New("invalid label key")
+	InvalidValueErr    = errors.New("invalid label value")
+)
+
+var (
+	// namePattern matches names of label keys and label values:
+	// alphanumeric characters at both ends with -, _ and . in between
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9]([-_.A-Za-z0-9]*[A-Za-z0-9])?$`)
+
+	// dnsLabelPattern matches a single DNS label of the prefix of a label key
+	dnsLabelPattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?$`)
+
+	// setPattern matches set-based requirements, e.g. "env in (prod,staging)"
+	setPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ValidateKey checks if the given string is a valid label key.
+// A key consists of an optional DNS prefix and a name separated by a slash, e.g. "example.com/team".
+// The prefix is a series of DNS labels separated by dots and must not be longer than MaxPrefixLength characters.
+// The name must not be longer than MaxNameLength characters, must begin and end with an alphanumeric character
+// and may contain -, _ and . in between
+func ValidateKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if err := validatePrefix(prefix); err != nil {
+			return fmt.Errorf("%w %q: %w", InvalidKeyErr, key, err)
+		}
+		name = rest
+	}
+	if name == "" {
+		return fmt.Errorf("%w %q: name is required", InvalidKeyErr, key)
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("%w %q: name must not be longer than %d characters", InvalidKeyErr, key, MaxNameLength)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w %q: name must begin and end with an alphanumeric character "+
+			"and may only contain alphanumeric characters, -, _ and .", InvalidKeyErr, key)
+	}
+	return nil
+}
+
+// validatePrefix checks if the given prefix of a label key is a valid DNS subdomain
+func validatePrefix(prefix string) error {
+	if prefix == "" {
+		return errors.New("prefix must not be empty")
+	}
+	if len(prefix) > MaxPrefixLength {
+		return fmt.Errorf("prefix must not be longer than %d characters", MaxPrefixLength)
+	}
+	for _, label := range strings.Split(prefix, ".") {
+		if len(label) > MaxNameLength || !dnsLabelPattern.MatchString(label) {
+			return fmt.Errorf("prefix must be a DNS subdomain, %q is not a valid DNS label", label)
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks if the given string is a valid label value.
+// A value may be empty, otherwise it must not be longer than MaxNameLength characters,
+// must begin and end with an alphanumeric character and may contain -, _ and . in between
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > MaxNameLength {
+		return fmt.Errorf("%w %q: must not be longer than %d characters", InvalidValueErr, value, MaxNameLength)
+	}
+	if !namePattern.MatchString(value) {
+		return fmt.Errorf("%w %q: must begin and end with an alphanumeric character "+
+			"and may only contain alphanumeric characters, -, _ and .", InvalidValueErr, value)
+	}
+	return nil
+}
+
+// Validate checks the key and values of the requirement as well as the number of values for its operator
+func (r Requirement) Validate() error {
+	if err := ValidateKey(r.Key); err != nil {
+		return fmt.Errorf("%w: %w", InvalidSelectorErr, err)
+	}
+	switch r.Operator {
+	case Equals, NotEquals:
+		if len(r.Values) != 1 {
+			return fmt.Errorf("%w: %s requires exactly one value for %q", InvalidSelectorErr, r.Operator, r.Key)
+		}
+	case In, NotIn:
+		if len(r.Values) == 0 {
+			return fmt.Errorf("%w: %s requires at least one value for %q", InvalidSelectorErr, r.Operator, r.Key)
+		}
+	case Exists, DoesNotExist:
+		if len(r.Values) != 0 {
+			return fmt.Errorf("%w: %s does not take values for %q", InvalidSelectorErr, r.Operator, r.Key)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operator %q", InvalidSelectorErr, r.Operator)
+	}
+	for _, value := range r.Values {
+		if err := ValidateValue(value); err != nil {
+			return fmt.Errorf("%w: %w", InvalidSelectorErr, err)
+		}
+	}
+	return nil
+}
+
+// Parse parses a label selector in the syntax of the Cloud Controller, e.g.
+// "env=prod,tier notin (free,trial),owner,!deprecated", and validates all keys and values.
+// An empty string results in an empty selector which matches everything
+func Parse(selector string) (*Selector, error) {
+	s := New()
+	if strings.TrimSpace(selector) == "" {
+		return s, nil
+	}
+	for _, part := range splitRequirements(selector) {
+		r, err := parseRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if s.add(r); s.err != nil {
+			return nil, s.err
+		}
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the selector is invalid
+func MustParse(selector string) *Selector {
+	s, err := Parse(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// splitRequirements splits the selector at all commas which are not part of a set of values
+func splitRequirements(selector string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// parseRequirement parses a single requirement of a label selector
+func parseRequirement(part string) (Requirement, error) {
+	if part == "" {
+		return Requirement{}, fmt.Errorf("%w: empty requirement", InvalidSelectorErr)
+	}
+	if match := setPattern.FindStringSubmatch(part); match != nil {
+		r := Requirement{Key: match[1], Operator: Operator(match[2])}
+		if strings.TrimSpace(match[3]) == "" {
+			return Requirement{}, fmt.Errorf("%w: %s requires at least one value for %q", InvalidSelectorErr, r.Operator, r.Key)
+		}
+		for _, value := range strings.Split(match[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(value))
+		}
+		return r, nil
+	}
+	if strings.ContainsAny(part, "()") {
+		return Requirement{}, fmt.Errorf("%w: cannot parse %q", InvalidSelectorErr, part)
+	}
+	if key, value, ok := strings.Cut(part, "!="); ok {
+		return Requirement{Key: strings.TrimSpace(key), Operator: NotEquals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+	if key, value, ok := strings.Cut(part, "=="); ok {
+		return Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+	if key, value, ok := strings.Cut(part, "="); ok {
+		return Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+	if key, ok := strings.CutPrefix(part, "!"); ok {
+		return Requirement{Key: strings.TrimSpace(key), Operator: DoesNotExist}, nil
+	}
+	return Requirement{Key: part, Operator: Exists}, nil
+}
@@ -0,0 +1,164 @@
+package labels
+
+import (
+	"slices"
+	"strings"
+)
+
+// Operator is the operator of a label selector requirement
+type Operator string
+
+//goland:noinspection GoUnusedConst
+const (
+	// Equals requires the label to have the given value (key=value)
+	Equals Operator = "="
+	// NotEquals requires the label to be missing or to have another value (key!=value)
+	NotEquals Operator = "!="
+	// In requires the label to have one of the given values (key in (v1,v2))
+	In Operator = "in"
+	// NotIn requires the label to be missing or to have none of the given values (key notin (v1,v2))
+	NotIn Operator = "notin"
+	// Exists requires the label to be set (key)
+	Exists Operator = "exists"
+	// DoesNotExist requires the label to be missing (!key)
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single requirement of a label selector
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// String returns the requirement in the label selector syntax of the Cloud Controller
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Equals, NotEquals:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, "")
+	case In, NotIn:
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(r.Values, ",") + ")"
+	case DoesNotExist:
+		return "!" + r.Key
+	}
+	return r.Key
+}
+
+// Matches returns true if the given labels fulfill the requirement
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Equals, In:
+		return ok && slices.Contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	case DoesNotExist:
+		return !ok
+	}
+	return ok
+}
+
+// Selector is a label selector which can be used to filter resources by their labels.
+// It can be built using the fluent methods (which validate keys and values) or parsed from a string using Parse.
+// The zero value is an empty selector which matches everything.
+//
+//	selector := labels.New().Eq("env", "prod").NotIn("tier", "free", "trial").Exists("owner")
+//	if err := selector.Err(); err != nil { ... }
+//	options := cf.ListSpacesOptions{Labels: selector}
+type Selector struct {
+	requirements []Requirement
+	err          error
+}
+
+// New returns an empty Selector
+func New() *Selector {
+	return &Selector{}
+}
+
+// Eq requires the label key to have the given value
+func (s *Selector) Eq(key, value string) *Selector {
+	return s.add(Requirement{Key: key, Operator: Equals, Values: []string{value}})
+}
+
+// NotEq requires the label key to be missing or to have a value other than the given value
+func (s *Selector) NotEq(key, value string) *Selector {
+	return s.add(Requirement{Key: key, Operator: NotEquals, Values: []string{value}})
+}
+
+// In requires the label key to have one of the given values
+func (s *Selector) In(key string, values ...string) *Selector {
+	return s.add(Requirement{Key: key, Operator: In, Values: values})
+}
+
+// NotIn requires the label key to be missing or to have none of the given values
+func (s *Selector) NotIn(key string, values ...string) *Selector {
+	return s.add(Requirement{Key: key, Operator: NotIn, Values: values})
+}
+
+// Exists requires the label key to be set
+func (s *Selector) Exists(key string) *Selector {
+	return s.add(Requirement{Key: key, Operator: Exists})
+}
+
+// NotExists requires the label key to be missing
+func (s *Selector) NotExists(key string) *Selector {
+	return s.add(Requirement{Key: key, Operator: DoesNotExist})
+}
+
+// add validates the requirement and adds it to the selector.
+// If the requirement is invalid, the error is kept and returned by Err
+func (s *Selector) add(r Requirement) *Selector {
+	if err := r.Validate(); err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return s
+	}
+	s.requirements = append(s.requirements, r)
+	return s
+}
+
+// Err returns the first validation error of the requirements added to the selector
+func (s *Selector) Err() error {
+	return s.err
+}
+
+// Build returns the selector in the syntax of the Cloud Controller
+// or the first validation error of the requirements added to the selector
+func (s *Selector) Build() (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.String(), nil
+}
+
+// Requirements returns a copy of the requirements of the selector
+func (s *Selector) Requirements() []Requirement {
+	return slices.Clone(s.requirements)
+}
+
+// Empty returns true if the selector has no requirements
+func (s *Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// String returns the selector in the label selector syntax of the Cloud Controller.
+// Invalid requirements are not included, use Build or Err to check for them
+func (s *Selector) String() string {
+	parts := make([]string, len(s.requirements))
+	for i, r := range s.requirements {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Matches returns true if the given labels fulfill all requirements of the selector.
+// This uses the same rules as the Cloud Controller, so it can be used to filter resources locally
+func (s *Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package models
+
+// Included contains the related resources which were requested using the include query parameter
+type Included struct {
+	Users         []User         `json:"users,omitempty"`
+	Spaces        []Space        `json:"spaces,omitempty"`
+	Organizations []Organization `json:"organizations,omitempty"`
+}
+
+// Append adds the resources of other to the included resources, skipping resources which are already included
+func (i *Included) Append(other Included) {
+	i.Users = appendMissing(i.Users, other.Users, func(u User) string { return u.Guid })
+	i.Spaces = appendMissing(i.Spaces, other.Spaces, func(s Space) string { return s.Guid })
+	i.Organizations = appendMissing(i.Organizations, other.Organizations, func(o Organization) string { return o.Guid })
+}
+
+// appendMissing appends all resources of other to resources which are not part of resources yet
+func appendMissing[T any](resources, other []T, guid func(T) string) []T {
+	known := make(map[string]struct{}, len(resources))
+	for _, resource := range resources {
+		known[guid(resource)] = struct{}{}
+	}
+	for _, resource := range other {
+		if _, ok := known[guid(resource)]; !ok {
+			known[guid(resource)] = struct{}{}
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// User returns the included user with the given GUID or nil if it is not included
+func (i *Included) User(guid string) *User {
+	for idx := range i.Users {
+		if i.Users[idx].Guid == guid {
+			return &i.Users[idx]
+		}
+	}
+	return nil
+}
+
+// Space returns the included space with the given GUID or nil if it is not included
+func (i *Included) Space(guid string) *Space {
+	for idx := range i.Spaces {
+		if i.Spaces[idx].Guid == guid {
+			return &i.Spaces[idx]
+		}
+	}
+	return nil
+}
+
+// Organization returns the included organization with the given GUID or nil if it is not included
+func (i *Included) Organization(guid string) *Organization {
+	for idx := range i.Organizations {
+		if i.Organizations[idx].Guid == guid {
+			return &i.Organizations[idx]
+		}
+	}
+	return nil
+}
+
+// EnrichedRole is a role joined with its included user, space and organization.
+// Related resources which were not included are nil
+type EnrichedRole struct {
+	Role
+	User         *User
+	Space        *Space
+	Organization *Organization
+}
+
+// Username returns the username of the role's user or an empty string if the user was not included
+func (r EnrichedRole) Username() string {
+	if r.User == nil {
+		return ""
+	}
+	return r.User.Username
+}
+
+// SpaceName returns the name of the role's space or an empty string if the role is not a space role
+// or the space was not included
+func (r EnrichedRole) SpaceName() string {
+	if r.Space == nil {
+		return ""
+	}
+	return r.Space.Name
+}
+
+// OrganizationName returns the name of the role's organization (or the organization of the role's space)
+// or an empty string if the organization was not included
+func (r EnrichedRole) OrganizationName() string {
+	if r.Organization == nil {
+		return ""
+	}
+	return r.Organization.Name
+}
+
+// EnrichRole joins the role with its related resources of the included resources.
+// For space roles, the organization of the space is used if it is included
+func (i *Included) EnrichRole(role Role) EnrichedRole {
+	return i.index().enrichRole(role)
+}
+
+// EnrichRoles is like EnrichRole but for multiple roles
+func (i *Included) EnrichRoles(roles []Role) []EnrichedRole {
+	index := i.index()
+	result := make([]EnrichedRole, len(roles))
+	for idx, role := range roles {
+		result[idx] = index.enrichRole(role)
+	}
+	return result
+}
+
+// EnrichedSpace is a space joined with its included organization.
+// The organization is nil if it was not included
+type EnrichedSpace struct {
+	Space
+	Organization *Organization
+}
+
+// OrganizationName returns the name of the space's organization or an empty string if it was not included
+func (s EnrichedSpace) OrganizationName() string {
+	if s.Organization == nil {
+		return ""
+	}
+	return s.Organization.Name
+}
+
+// EnrichSpace joins the space with its organization of the included resources
+func (i *Included) EnrichSpace(space Space) EnrichedSpace {
+	return i.index().enrichSpace(space)
+}
+
+// EnrichSpaces is like EnrichSpace but for multiple spaces
+func (i *Included) EnrichSpaces(spaces []Space) []EnrichedSpace {
+	index := i.index()
+	result := make([]EnrichedSpace, len(spaces))
+	for idx, space := range spaces {
+		result[idx] = index.enrichSpace(space)
+	}
+	return result
+}
+
+// includedIndex maps the GUIDs of included resources to the resources
+type includedIndex struct {
+	users         map[string]*User
+	spaces        map[string]*Space
+	organizations map[string]*Organization
+}
+
+// index returns an index of the included resources for joining many resources at once
+func (i *Included) index() includedIndex {
+	index := includedIndex{
+		users:         make(map[string]*User, len(i.Users)),
+		spaces:        make(map[string]*Space, len(i.Spaces)),
+		organizations: make(map[string]*Organization, len(i.Organizations)),
+	}
+	for idx := range i.Users {
+		index.users[i.Users[idx].Guid] = &i.Users[idx]
+	}
+	for idx := range i.Spaces {
+		index.spaces[i.Spaces[idx].Guid] = &i.Spaces[idx]
+	}
+	for idx := range i.Organizations {
+		index.organizations[i.Organizations[idx].Guid] = &i.Organizations[idx]
+	}
+	return index
+}
+
+// enrichRole joins the role with its related resources
+func (index includedIndex) enrichRole(role Role) EnrichedRole {
+	enriched := EnrichedRole{
+		Role: role,
+		User: index.users[role.GetUserID()],
+	}
+	if guid, ok := role.GetSpaceID(); ok {
+		enriched.Space = index.spaces[guid]
+		if enriched.Space != nil {
+			enriched.Organization = index.organizations[enriched.Space.Relationships.Organization.Data.Guid]
+		}
+	}
+	if guid, ok := role.GetOrganizationID(); ok {
+		enriched.Organization = index.organizations[guid]
+	}
+	return enriched
+}
+
+// enrichSpace joins the space with its organization
+func (index includedIndex) enrichSpace(space Space) EnrichedSpace {
+	return EnrichedSpace{
+		Space:        space,
+		Organization: index.organizations[space.Relationships.Organization.Data.Guid],
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+// RootLink is a link of the Cloud Controller root document
+type RootLink struct {
+	Href string `json:"href"`
+	Meta struct {
+		Version            string `json:"version,omitempty"`
+		HostKeyFingerprint string `json:"host_key_fingerprint,omitempty"`
+		OAuthClient        string `json:"oauth_client,omitempty"`
+	} `json:"meta"`
+}
+
+// Root is the root document of the Cloud Controller (GET /) which links to all other components of the foundation
+type Root struct {
+	Links struct {
+		Self              *RootLink `json:"self"`
+		CloudControllerV2 *RootLink `json:"cloud_controller_v2"`
+		CloudControllerV3 *RootLink `json:"cloud_controller_v3"`
+		NetworkPolicyV0   *RootLink `json:"network_policy_v0"`
+		NetworkPolicyV1   *RootLink `json:"network_policy_v1"`
+		Login             *RootLink `json:"login"`
+		UAA               *RootLink `json:"uaa"`
+		Credhub           *RootLink `json:"credhub"`
+		Routing           *RootLink `json:"routing"`
+		Logging           *RootLink `json:"logging"`
+		LogCache          *RootLink `json:"log_cache"`
+		LogStream         *RootLink `json:"log_stream"`
+		AppSSH            *RootLink `json:"app_ssh"`
+	} `json:"links"`
+}
+
+// CloudControllerVersion returns the version of the Cloud Controller V3 API, e.g. "3.158.0"
+func (r Root) CloudControllerVersion() string {
+	if r.Links.CloudControllerV3 == nil {
+		return ""
+	}
+	return r.Links.CloudControllerV3.Meta.Version
+}
+
+// Info is the information about the foundation returned by GET /v3/info
+type Info struct {
+	Build       string `json:"build"`
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	CLIVersion  struct {
+		Minimum     string `json:"minimum"`
+		Recommended string `json:"recommended"`
+	} `json:"cli_version"`
+	Custom        map[string]any `json:"custom"`
+	OSBAPIVersion string         `json:"osbapi_version"`
+	Links         struct {
+		Self struct {
+			Href string `json:"href"`
+		} `json:"self"`
+		Support struct {
+			Href string `json:"href"`
+		} `json:"support"`
+	} `json:"links"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// JobState is the state of an asynchronous job
+type JobState string
+
+//goland:noinspection GoUnusedConst
+const (
+	JobStateProcessing JobState = "PROCESSING"
+	JobStatePolling    JobState = "POLLING"
+	JobStateComplete   JobState = "COMPLETE"
+	JobStateFailed     JobState = "FAILED"
+)
+
+// JobError is an error which caused a job to fail
+type JobError struct {
+	Detail string `json:"detail"`
+	Title  string `json:"title"`
+	Code   int    `json:"code"`
+}
+
+// JobWarning is a warning which occurred while processing a job
+type JobWarning struct {
+	Detail string `json:"detail"`
+}
+
+// Job is an asynchronous operation of the Cloud Controller, e.g. the deletion of a resource
+type Job struct {
+	Guid      string       `json:"guid"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Operation string       `json:"operation"`
+	State     JobState     `json:"state"`
+	Errors    []JobError   `json:"errors"`
+	Warnings  []JobWarning `json:"warnings"`
+	Links     struct {
+		Self struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// IsFinished returns true if the job is either complete or failed
+func (j Job) IsFinished() bool {
+	return j.State == JobStateComplete || j.State == JobStateFailed
+}
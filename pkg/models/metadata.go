@@ -0,0 +1,119 @@
+package models
+
+// Metadata contains the labels and annotations of a resource
+type Metadata struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Patch returns a MetadataPatch which sets all labels and annotations of the metadata
+func (m Metadata) Patch() *MetadataPatch {
+	patch := &MetadataPatch{}
+	for key, value := range m.Labels {
+		patch.SetLabel(key, value)
+	}
+	for key, value := range m.Annotations {
+		patch.SetAnnotation(key, value)
+	}
+	return patch
+}
+
+// Diff returns a MetadataPatch which changes the metadata to the desired metadata.
+// Labels and annotations which are missing in the desired metadata are deleted, so changes to a fetched resource's
+// metadata can be applied using:
+//
+//	desired := space.Metadata.Clone()
+//	delete(desired.Labels, "obsolete")
+//	desired.Labels["env"] = "prod"
+//	client.UpdateSpace(space.Guid, cf.UpdateSpaceOptions{Metadata: space.Metadata.Diff(desired)})
+func (m Metadata) Diff(desired Metadata) *MetadataPatch {
+	return &MetadataPatch{
+		Labels:      diff(m.Labels, desired.Labels),
+		Annotations: diff(m.Annotations, desired.Annotations),
+	}
+}
+
+// Clone returns a deep copy of the metadata. The maps of the copy are never nil
+func (m Metadata) Clone() Metadata {
+	clone := Metadata{
+		Labels:      make(map[string]string, len(m.Labels)),
+		Annotations: make(map[string]string, len(m.Annotations)),
+	}
+	for key, value := range m.Labels {
+		clone.Labels[key] = value
+	}
+	for key, value := range m.Annotations {
+		clone.Annotations[key] = value
+	}
+	return clone
+}
+
+// diff returns the changes from current to desired. Deleted keys have a nil value
+func diff(current, desired map[string]string) map[string]*string {
+	var changes map[string]*string
+	set := func(key string, value *string) {
+		if changes == nil {
+			changes = make(map[string]*string)
+		}
+		changes[key] = value
+	}
+	for key, value := range desired {
+		if old, ok := current[key]; !ok || old != value {
+			set(key, &value)
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			set(key, nil)
+		}
+	}
+	return changes
+}
+
+// MetadataPatch is a partial update of the labels and annotations of a resource.
+// Keys which are not part of the patch are left unchanged, keys with a nil value are deleted (sent as JSON null)
+type MetadataPatch struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// SetLabel sets the label key to the given value
+func (p *MetadataPatch) SetLabel(key, value string) *MetadataPatch {
+	if p.Labels == nil {
+		p.Labels = make(map[string]*string)
+	}
+	p.Labels[key] = &value
+	return p
+}
+
+// DeleteLabel deletes the label key
+func (p *MetadataPatch) DeleteLabel(key string) *MetadataPatch {
+	if p.Labels == nil {
+		p.Labels = make(map[string]*string)
+	}
+	p.Labels[key] = nil
+	return p
+}
+
+// SetAnnotation sets the annotation key to the given value
+func (p *MetadataPatch) SetAnnotation(key, value string) *MetadataPatch {
+	if p.Annotations == nil {
+		p.Annotations = make(map[string]*string)
+	}
+	p.Annotations[key] = &value
+	return p
+}
+
+// DeleteAnnotation deletes the annotation key
+func (p *MetadataPatch) DeleteAnnotation(key string) *MetadataPatch {
+	if p.Annotations == nil {
+		p.Annotations = make(map[string]*string)
+	}
+	p.Annotations[key] = nil
+	return p
+}
+
+// IsEmpty returns true if the patch doesn't change anything
+func (p *MetadataPatch) IsEmpty() bool {
+	return p == nil || (len(p.Labels) == 0 && len(p.Annotations) == 0)
+}
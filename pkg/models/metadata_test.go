@@ -0,0 +1,84 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/darmiel/go-cf-client/pkg/models"
+)
+
+// marshal returns the JSON encoding of the patch
+func marshal(t *testing.T, patch *models.MetadataPatch) string {
+	t.Helper()
+	data, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMetadataPatchMarshalsDeletedKeysAsNull(t *testing.T) {
+	patch := (&models.MetadataPatch{}).
+		SetLabel("env", "prod").
+		DeleteLabel("team").
+		DeleteAnnotation("note")
+	want := `{"labels":{"env":"prod","team":null},"annotations":{"note":null}}`
+	if got := marshal(t, patch); got != want {
+		t.Errorf("patch = %s, want %s", got, want)
+	}
+	if got := marshal(t, &models.MetadataPatch{}); got != "{}" {
+		t.Errorf("empty patch = %s, want {}", got)
+	}
+}
+
+func TestMetadataDiff(t *testing.T) {
+	current := models.Metadata{
+		Labels:      map[string]string{"env": "dev", "team": "core", "tier": "backend"},
+		Annotations: map[string]string{"note": "keep"},
+	}
+	tests := []struct {
+		name    string
+		current models.Metadata
+		desired models.Metadata
+		want    string
+	}{
+		{
+			name:    "unchanged",
+			current: current,
+			desired: current.Clone(),
+			want:    `{}`,
+		},
+		{
+			name:    "changed, added and deleted labels",
+			current: current,
+			desired: models.Metadata{
+				Labels:      map[string]string{"env": "prod", "team": "core", "region": "eu"},
+				Annotations: map[string]string{"note": "keep"},
+			},
+			want: `{"labels":{"env":"prod","region":"eu","tier":null}}`,
+		},
+		{
+			name:    "deleted everything",
+			current: current,
+			desired: models.Metadata{},
+			want:    `{"labels":{"env":null,"team":null,"tier":null},"annotations":{"note":null}}`,
+		},
+		{
+			name:    "from empty metadata",
+			current: models.Metadata{},
+			desired: models.Metadata{Annotations: map[string]string{"note": "new"}},
+			want:    `{"annotations":{"note":"new"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := tt.current.Diff(tt.desired)
+			if got := marshal(t, patch); got != tt.want {
+				t.Errorf("diff = %s, want %s", got, tt.want)
+			}
+			if empty := tt.want == "{}"; patch.IsEmpty() != empty {
+				t.Errorf("IsEmpty() = %v, want %v", patch.IsEmpty(), empty)
+			}
+		})
+	}
+}
@@ -16,13 +16,8 @@ type Organization struct {
 			} `json:"data"`
 		} `json:"quota"`
 	} `json:"relationships"`
-	Metadata struct {
-		Labels struct {
-		} `json:"labels"`
-		Annotations struct {
-		} `json:"annotations"`
-	} `json:"metadata"`
-	Links struct {
+	Metadata Metadata `json:"metadata"`
+	Links    struct {
 		Self struct {
 			Href string `json:"href"`
 		} `json:"self"`
@@ -33,10 +33,5 @@ type Space struct {
 			Method string `json:"method"`
 		} `json:"apply_manifest"`
 	} `json:"links"`
-	Metadata struct {
-		Labels struct {
-		} `json:"labels"`
-		Annotations struct {
-		} `json:"annotations"`
-	} `json:"metadata"`
+	Metadata Metadata `json:"metadata"`
 }
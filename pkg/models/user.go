@@ -10,13 +10,8 @@ type User struct {
 	Username         string    `json:"username"`
 	PresentationName string    `json:"presentation_name"`
 	Origin           string    `json:"origin"`
-	Metadata         struct {
-		Labels struct {
-		} `json:"labels"`
-		Annotations struct {
-		} `json:"annotations"`
-	} `json:"metadata"`
-	Links struct {
+	Metadata         Metadata  `json:"metadata"`
+	Links            struct {
 		Self struct {
 			Href string `json:"href"`
 		} `json:"self"`